@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"fmt"
+)
+
+//-------------------------------------------------------------------------
+// Action
+//-------------------------------------------------------------------------
+
+// Abstract player input, frontends translate their own events (keys,
+// buttons, bots' decisions) into these and feed them to GameSession.
+type Action int
+
+const (
+	MoveLeft Action = iota
+	MoveRight
+	RotateCW
+	RotateCCW
+	SoftDrop // held, see GameSession.ReleaseAction
+	HardDrop
+	Hold
+	Pause
+)
+
+var actionNames = [...]string{
+	MoveLeft:  "move-left",
+	MoveRight: "move-right",
+	RotateCW:  "rotate-cw",
+	RotateCCW: "rotate-ccw",
+	SoftDrop:  "soft-drop",
+	HardDrop:  "hard-drop",
+	Hold:      "hold",
+	Pause:     "pause",
+}
+
+func (self Action) String() string {
+	if self < 0 || int(self) >= len(actionNames) {
+		return "unknown"
+	}
+	return actionNames[self]
+}
+
+// looks up an action by its name (as returned by String)
+func ParseAction(name string) (Action, bool) {
+	for i, n := range actionNames {
+		if n == name {
+			return Action(i), true
+		}
+	}
+	return 0, false
+}
+
+// actions are stored by name in replays
+func (self Action) MarshalText() ([]byte, error) {
+	if self < 0 || int(self) >= len(actionNames) {
+		return nil, fmt.Errorf("unknown action: %d", int(self))
+	}
+	return []byte(actionNames[self]), nil
+}
+
+func (self *Action) UnmarshalText(text []byte) error {
+	action, ok := ParseAction(string(text))
+	if !ok {
+		return fmt.Errorf("unknown action: %q", text)
+	}
+	*self = action
+	return nil
+}
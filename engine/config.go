@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"fmt"
+)
+
+//-------------------------------------------------------------------------
+// Config
+//-------------------------------------------------------------------------
+
+// Rule settings of a GameSession, they stay the same across Reset calls.
+type Config struct {
+	// name of the game mode, see Modes
+	Mode string
+
+	// size of the field in blocks
+	Width  int
+	Height int
+
+	// lines to clear in sprint mode
+	LineGoal int
+
+	// length of an ultra game in milliseconds
+	TimeLimit uint32
+
+	// rows of garbage the field starts with in dig mode
+	GarbageRows int
+
+	// initial level (1..MaxLevel)
+	Level int
+
+	// name of the piece generator, see Generators
+	Randomizer string
+
+	// name of the rotation system, see RotationSystems
+	Rotation string
+
+	// how long (in milliseconds) a figure may stay on the ground before it
+	// locks and how many times moving or rotating it restarts that delay
+	LockDelay  uint32
+	LockResets int
+
+	// how long (in milliseconds) complete lines stay on the field before
+	// they collapse and the next figure spawns
+	LineClearDelay uint32
+
+	// Delayed auto shift and auto repeat rate in milliseconds, see
+	// GameSession.autoShift
+	DAS uint32
+	ARR uint32
+
+	// how much faster figures fall while soft drop is held
+	SoftDropFactor int
+
+	// number of upcoming figures shown to the player (1..MaxPreviews)
+	Previews int
+}
+
+const MaxPreviews = 6
+
+// field size limits
+const (
+	MinWidth  = 4
+	MaxWidth  = 40
+	MinHeight = 8
+	MaxHeight = 40
+)
+
+// rows at the top of the field the starting garbage in dig mode leaves
+// free, for figures to spawn
+const garbageFreeRows = 5
+
+func DefaultConfig() *Config {
+	return &Config{
+		Mode:           "marathon",
+		Width:          10,
+		Height:         25,
+		LineGoal:       40,
+		TimeLimit:      120000,
+		GarbageRows:    10,
+		Level:          1,
+		Randomizer:     "classic",
+		Rotation:       "classic",
+		LockDelay:      500,
+		LockResets:     15,
+		LineClearDelay: 300,
+		Previews:       1,
+		DAS:            170,
+		ARR:            50,
+		SoftDropFactor: 20,
+	}
+}
+
+func (self *Config) Validate() error {
+	if _, ok := Modes[self.Mode]; !ok {
+		return fmt.Errorf("unknown game mode: %q", self.Mode)
+	}
+	if self.LineGoal < 1 {
+		return fmt.Errorf("line goal must be at least 1, got: %d", self.LineGoal)
+	}
+	if self.TimeLimit == 0 {
+		return fmt.Errorf("time limit must not be 0")
+	}
+	if self.Width < MinWidth || self.Width > MaxWidth {
+		return fmt.Errorf("field width must be in %d..%d range, got: %d",
+			MinWidth, MaxWidth, self.Width)
+	}
+	if self.Height < MinHeight || self.Height > MaxHeight {
+		return fmt.Errorf("field height must be in %d..%d range, got: %d",
+			MinHeight, MaxHeight, self.Height)
+	}
+	maxGarbage := self.Height - garbageFreeRows
+	if self.Mode == "dig" && (self.GarbageRows < 1 || self.GarbageRows > maxGarbage) {
+		return fmt.Errorf("garbage rows must be in 1..%d range for a field %d rows high, got: %d",
+			maxGarbage, self.Height, self.GarbageRows)
+	}
+	if _, ok := Generators[self.Randomizer]; !ok {
+		return fmt.Errorf("unknown randomizer: %q", self.Randomizer)
+	}
+	if _, ok := RotationSystems[self.Rotation]; !ok {
+		return fmt.Errorf("unknown rotation system: %q", self.Rotation)
+	}
+	if self.LockResets < 0 {
+		return fmt.Errorf("negative lock resets: %d", self.LockResets)
+	}
+	if self.SoftDropFactor < 1 {
+		return fmt.Errorf("soft drop factor must be at least 1, got: %d",
+			self.SoftDropFactor)
+	}
+	if self.Previews < 1 || self.Previews > MaxPreviews {
+		return fmt.Errorf("number of previews must be in 1..%d range, got: %d",
+			MaxPreviews, self.Previews)
+	}
+	return nil
+}
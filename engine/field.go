@@ -0,0 +1,193 @@
+package engine
+
+//-------------------------------------------------------------------------
+// TetrisField
+//-------------------------------------------------------------------------
+
+type TetrisField struct {
+	Width  int
+	Height int
+	Blocks []TetrisBlock
+}
+
+func NewTetrisField(w, h int) *TetrisField {
+	return &TetrisField{w, h, make([]TetrisBlock, w*h)}
+}
+
+func (self *TetrisField) Clear() {
+	for i := 0; i < self.Width*self.Height; i++ {
+		self.Blocks[i] = TetrisBlock{}
+	}
+}
+
+func (self *TetrisField) Grayify() {
+	for i := 0; i < self.Width*self.Height; i++ {
+		if !self.Blocks[i].Filled {
+			continue
+		}
+
+		c := &self.Blocks[i].Color
+		if c.R != 80 {
+			if c.R < 80 {
+				c.R++
+			} else {
+				c.R--
+			}
+		}
+		if c.G != 80 {
+			if c.G < 80 {
+				c.G++
+			} else {
+				c.G--
+			}
+		}
+		if c.B != 80 {
+			if c.B < 80 {
+				c.B++
+			} else {
+				c.B--
+			}
+		}
+	}
+}
+
+func (self *TetrisField) Collide(figure *TetrisFigure) bool {
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			offset := y*4 + x
+			if !figure.Blocks[offset].Filled {
+				continue
+			}
+
+			fx, fy := figure.X+x, figure.Y+y
+			if fx < 0 || fy < 0 || fx >= self.Width || fy >= self.Height {
+				return true
+			}
+			fieldOffset := fy*self.Width + fx
+			if self.Blocks[fieldOffset].Filled {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// whether the cell is filled, everything outside of the field counts as
+// filled too
+func (self *TetrisField) occupied(x, y int) bool {
+	if x < 0 || y < 0 || x >= self.Width || y >= self.Height {
+		return true
+	}
+	return self.Blocks[y*self.Width+x].Filled
+}
+
+// returns how many rows the figure can fall before it hits something
+func (self *TetrisField) DropDistance(figure *TetrisFigure) int {
+	f := *figure
+	for {
+		f.Y++
+		if self.Collide(&f) {
+			return f.Y - 1 - figure.Y
+		}
+	}
+}
+
+func (self *TetrisField) StepCollideAndMerge(figure *TetrisFigure) bool {
+	figure.Y++
+	if !self.Collide(figure) {
+		return false
+	}
+	figure.Y--
+
+	self.Merge(figure)
+	return true
+}
+
+// copy figure's blocks onto the field, the figure must not collide
+func (self *TetrisField) Merge(figure *TetrisFigure) {
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			offset := y*4 + x
+			if !figure.Blocks[offset].Filled {
+				continue
+			}
+			fx, fy := figure.X+x, figure.Y+y
+			fieldOffset := fy*self.Width + fx
+			self.Blocks[fieldOffset] = figure.Blocks[offset]
+		}
+	}
+}
+
+// check if there are any complete lines on the field and remove them
+// returns the number of lines removed
+func (self *TetrisField) CheckForLines() int {
+	rows := self.FullLines()
+	self.RemoveLines(rows)
+	return len(rows)
+}
+
+var garbageColor = TetrisBlockColor{R: 120, G: 120, B: 120}
+
+// Moves everything one row up and fills the bottom row with garbage leaving
+// a hole at the given column. Returns false if that pushed blocks out of the
+// field.
+func (self *TetrisField) PushGarbage(hole int) bool {
+	fits := true
+	for x := 0; x < self.Width; x++ {
+		if self.Blocks[x].Filled {
+			fits = false
+		}
+	}
+	copy(self.Blocks, self.Blocks[self.Width:])
+	bottom := self.Blocks[(self.Height-1)*self.Width:]
+	for x := range bottom {
+		bottom[x] = TetrisBlock{Filled: x != hole, Color: garbageColor, Garbage: x != hole}
+	}
+	return fits
+}
+
+// whether the row has any garbage blocks in it
+func (self *TetrisField) garbageRow(y int) bool {
+	for x := 0; x < self.Width; x++ {
+		if self.Blocks[y*self.Width+x].Garbage {
+			return true
+		}
+	}
+	return false
+}
+
+// returns indices of complete rows, top to bottom
+func (self *TetrisField) FullLines() []int {
+	var rows []int
+	for y := 0; y < self.Height; y++ {
+		full := true
+		for x := 0; x < self.Width; x++ {
+			offset := y*self.Width + x
+			if !self.Blocks[offset].Filled {
+				full = false
+				break
+			}
+		}
+
+		if full {
+			rows = append(rows, y)
+		}
+	}
+	return rows
+}
+
+// removes given rows (sorted top to bottom) moving everything above them
+// down
+func (self *TetrisField) RemoveLines(rows []int) {
+	for _, y := range rows {
+		for y2 := y - 1; y2 >= 0; y2-- {
+			for x := 0; x < self.Width; x++ {
+				offset := y2*self.Width + x
+				self.Blocks[offset+self.Width] = self.Blocks[offset]
+			}
+		}
+		for x := 0; x < self.Width; x++ {
+			self.Blocks[x] = TetrisBlock{}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package engine
+
+// ah, the source code is utf-8, let's use some UNICODE box-drawing here:
+
+// ████
+//   ████
+const specN = `
+0110
+1200
+0000
+0000
+`
+
+//   ████
+// ████
+const specNMirrored = `
+1100
+0210
+0000
+0000
+`
+
+//   ██
+// ██████
+const specT = `
+0100
+1210
+0000
+0000
+`
+
+// ████████
+const specI = `
+0100
+0200
+0100
+0100
+`
+
+// ████
+// ████
+const specB = `
+1100
+1100
+0000
+0000
+`
+
+// ██████
+// ██
+const specL = `
+0100
+0200
+0110
+0000
+`
+
+// ██████
+//     ██
+const specLMirrored = `
+0100
+0200
+1100
+0000
+`
+
+var specColors = [...]TetrisBlockColor{
+	TetrisBlockColor{255, 0, 0},
+	TetrisBlockColor{0, 255, 0},
+	TetrisBlockColor{100, 100, 255},
+	TetrisBlockColor{255, 255, 255},
+	TetrisBlockColor{255, 0, 255},
+	TetrisBlockColor{255, 255, 0},
+	TetrisBlockColor{0, 255, 255}}
+
+// figure classes the rules care about (indices into specs)
+const (
+	classT = 2
+	classI = 3
+	classO = 4
+)
+
+var specs = [...]string{
+	specN,
+	specNMirrored,
+	specT,
+	specI,
+	specB,
+	specL,
+	specLMirrored}
+
+//-------------------------------------------------------------------------
+// TetrisBlockColor
+//-------------------------------------------------------------------------
+
+type TetrisBlockColor struct {
+	R, G, B byte
+}
+
+//-------------------------------------------------------------------------
+// TetrisBlock
+//-------------------------------------------------------------------------
+
+type TetrisBlock struct {
+	Filled  bool
+	Color   TetrisBlockColor
+	Garbage bool // part of a garbage row rather than of a figure
+}
+
+//-------------------------------------------------------------------------
+// TetrisFigure
+//-------------------------------------------------------------------------
+
+type TetrisFigure struct {
+	// center of the figure (valid range: 0..3 0..3)
+	CenterX, CenterY int
+
+	// position in blocks relative to top left tetris field block
+	X, Y   int
+	Blocks [16]TetrisBlock
+	Class  uint32
+
+	// orientation state (RotationSpawn, RotationRight, ...)
+	Rotation int
+}
+
+// build figure out of spec
+func NewTetrisFigure(spec string, color TetrisBlockColor) *TetrisFigure {
+	figure := new(TetrisFigure)
+	figure.X = 3
+	figure.CenterX = -1
+	figure.CenterY = -1
+
+	i := 0
+	for _, c := range spec {
+		switch c {
+		case '2':
+			figure.CenterX = i % 4
+			figure.CenterY = i / 4
+			fallthrough
+		case '1':
+			figure.Blocks[i].Filled = true
+			figure.Blocks[i].Color = color
+			fallthrough
+		case '0':
+			i++
+		}
+	}
+	return figure
+}
+
+// build figure of the given class (index into specs)
+func NewTetrisFigureOfClass(class uint32) *TetrisFigure {
+	f := NewTetrisFigure(specs[class], specColors[class])
+	f.Class = class
+	return f
+}
+
+func (self *TetrisFigure) SetColor(color TetrisBlockColor) {
+	for i := 0; i < 16; i++ {
+		if !self.Blocks[i].Filled {
+			continue
+		}
+
+		self.Blocks[i].Color = color
+	}
+}
+
+func rotateCWBlock(x, y int) (ox, oy int) {
+	ox, oy = -y, x
+	return
+}
+
+func rotateCCWBlock(x, y int) (ox, oy int) {
+	ox, oy = y, -x
+	return
+}
+
+type RotateFunc func(int, int) (int, int)
+
+func (self *TetrisFigure) GetRotationsNum(rotateBlock RotateFunc) int {
+	const (
+		Rotate1 uint = 1 << iota
+		Rotate2
+		Rotate3
+		Rotate4
+	)
+	validRotations := ^uint(0)
+	// first we rotate each visible block four times around the center
+	// and checking whether each rotation is valid, then we make a list
+	// of valid rotation counts (like: [3, 4] or [1, 2, 3, 4])
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			blockMask := uint(0)
+			if !self.Blocks[y*4+x].Filled {
+				continue
+			}
+			blockX, blockY := x-self.CenterX, y-self.CenterY
+			for i := 0; i < 4; i++ {
+				blockX, blockY = rotateBlock(blockX, blockY)
+				rbx, rby := self.CenterX+blockX, self.CenterY+blockY
+
+				// check whether a rotation is valid an record it
+				if rbx >= 0 && rbx <= 4 && rby >= 0 && rby <= 4 {
+					blockMask |= 1 << uint(i)
+				}
+			}
+
+			// apply mask to global mask
+			validRotations &= blockMask
+		}
+	}
+
+	// at this point we have valid rotations list
+	rotationsNum := 0
+	// determine number of rotations
+	switch {
+	case validRotations&Rotate1 > 0:
+		rotationsNum = 1
+	case validRotations&Rotate2 > 0:
+		rotationsNum = 2
+	case validRotations&Rotate3 > 0:
+		rotationsNum = 3
+	case validRotations&Rotate4 > 0:
+		rotationsNum = 4
+	}
+
+	return rotationsNum
+}
+
+func (self *TetrisFigure) Rotate(rotateBlock RotateFunc) {
+	// if there is no center, then the figure cannot be rotated
+	if self.CenterX == -1 {
+		return
+	}
+
+	rotationsNum := self.GetRotationsNum(rotateBlock)
+
+	var newBlocks [16]TetrisBlock
+	for i := 0; i < 16; i++ {
+		if !self.Blocks[i].Filled {
+			continue
+		}
+		x := i % 4
+		y := i / 4
+		x, y = x-self.CenterX, y-self.CenterY
+
+		for j := 0; j < rotationsNum; j++ {
+			x, y = rotateBlock(x, y)
+		}
+
+		x, y = x+self.CenterX, y+self.CenterY
+		newBlocks[y*4+x] = self.Blocks[i]
+	}
+	self.Blocks = newBlocks
+}
@@ -0,0 +1,83 @@
+package engine
+
+//-------------------------------------------------------------------------
+// Finesse
+//-------------------------------------------------------------------------
+
+// Returns the least number of inputs needed to bring a freshly spawned
+// figure of the same class to the columns and orientation the figure is in.
+// Inputs are single moves, rotations and holding left or right until the
+// wall (DAS). Obstacles are ignored, the way is searched on an empty field
+// of the given size. Returns -1 if the placement is unreachable that way
+// (e.g. a tuck under an overhang could be needed).
+func FinesseInputs(rotation RotationSystem, width, height int, figure *TetrisFigure) int {
+	type state struct{ x, y, rotation int }
+
+	field := NewTetrisField(width, height)
+	target := figureColumns(figure)
+	start := spawnFigure(rotation, figure.Class, width)
+	if field.Collide(start) {
+		return -1
+	}
+
+	visited := map[state]bool{{start.X, start.Y, start.Rotation}: true}
+	queue := []*TetrisFigure{start}
+	for inputs := 0; len(queue) > 0; inputs++ {
+		var next []*TetrisFigure
+		for _, f := range queue {
+			if figureColumns(f) == target {
+				return inputs
+			}
+
+			var moves []*TetrisFigure
+			for _, dir := range [...]int{-1, 1} {
+				moved := *f
+				for moved.X += dir; !field.Collide(&moved); moved.X += dir {
+					if moved.X == f.X+dir {
+						single := moved
+						moves = append(moves, &single)
+					}
+				}
+				moved.X -= dir
+				moves = append(moves, &moved) // DAS to the wall
+			}
+			for _, cw := range [...]bool{true, false} {
+				rotated := *f
+				if _, ok := rotation.Rotate(field, &rotated, cw); ok {
+					moves = append(moves, &rotated)
+				}
+			}
+
+			for _, m := range moves {
+				s := state{m.X, m.Y, m.Rotation}
+				if !visited[s] {
+					visited[s] = true
+					next = append(next, m)
+				}
+			}
+		}
+		queue = next
+	}
+	return -1
+}
+
+// the shape of the figure and the columns it takes, but not its height, as
+// a comparable value
+func figureColumns(figure *TetrisFigure) [16]int {
+	var cells [16]int
+	minY := 4
+	for i, b := range figure.Blocks {
+		if b.Filled && i/4 < minY {
+			minY = i / 4
+		}
+	}
+	n := 0
+	for i, b := range figure.Blocks {
+		if b.Filled {
+			x, y := figure.X+i%4, i/4-minY
+			cells[n] = y*1000 + x + 1
+			n++
+		}
+	}
+	return cells
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"math/rand"
+)
+
+//-------------------------------------------------------------------------
+// Generator
+//-------------------------------------------------------------------------
+
+// Generator produces the sequence of figure classes (indices into specs)
+// the session spawns. All randomness must come from the passed rng, that
+// way a seed fully determines the piece sequence.
+type Generator interface {
+	Next() uint32
+
+	// returns a copy of the generator drawing from the given rng, used by
+	// session snapshots
+	Clone(rng *rand.Rand) Generator
+}
+
+// Known generators by name, the name is what goes into Config.Randomizer.
+var Generators = map[string]func(rng *rand.Rand) Generator{
+	"classic": NewClassicGenerator,
+	"bag":     NewBagGenerator,
+}
+
+//-------------------------------------------------------------------------
+// ClassicGenerator
+//-------------------------------------------------------------------------
+
+// Uniformly random figures, rerolls when the new figure is the same as the
+// previous one.
+type ClassicGenerator struct {
+	rand  *rand.Rand
+	last  uint32
+	first bool
+}
+
+func NewClassicGenerator(rng *rand.Rand) Generator {
+	return &ClassicGenerator{rand: rng, first: true}
+}
+
+func (self *ClassicGenerator) Next() uint32 {
+	var ri uint32
+	for {
+		ri = self.rand.Uint32() % uint32(len(specs))
+		if self.first || ri != self.last {
+			break
+		}
+	}
+	self.first = false
+	self.last = ri
+	return ri
+}
+
+func (self *ClassicGenerator) Clone(rng *rand.Rand) Generator {
+	c := *self
+	c.rand = rng
+	return &c
+}
+
+//-------------------------------------------------------------------------
+// BagGenerator
+//-------------------------------------------------------------------------
+
+// The 7-bag randomizer: all seven figures are put into a bag which is then
+// shuffled and emptied one by one, after that a new bag is made.
+type BagGenerator struct {
+	rand *rand.Rand
+	bag  [len(specs)]uint32
+	n    int // figures left in the bag
+}
+
+func NewBagGenerator(rng *rand.Rand) Generator {
+	return &BagGenerator{rand: rng}
+}
+
+func (self *BagGenerator) Next() uint32 {
+	if self.n == 0 {
+		for i, j := range self.rand.Perm(len(specs)) {
+			self.bag[i] = uint32(j)
+		}
+		self.n = len(specs)
+	}
+	self.n--
+	return self.bag[self.n]
+}
+
+func (self *BagGenerator) Clone(rng *rand.Rand) Generator {
+	c := *self
+	c.rand = rng
+	return &c
+}
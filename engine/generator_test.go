@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBagGenerator(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		g := NewBagGenerator(rand.New(rand.NewSource(seed)))
+		for bag := 0; bag < 100; bag++ {
+			var seen [len(specs)]int
+			for i := 0; i < len(specs); i++ {
+				seen[g.Next()]++
+			}
+			for class, n := range seen {
+				if n != 1 {
+					t.Fatalf("seed %d, bag %d: class %d appears %d times",
+						seed, bag, class, n)
+				}
+			}
+		}
+	}
+}
+
+func TestClassicGeneratorNoRepeats(t *testing.T) {
+	g := NewClassicGenerator(rand.New(rand.NewSource(1)))
+	last := g.Next()
+	for i := 0; i < 1000; i++ {
+		cur := g.Next()
+		if cur == last {
+			t.Fatalf("figure %d repeats class %d", i, cur)
+		}
+		last = cur
+	}
+}
+
+func TestGeneratorsAreDeterministic(t *testing.T) {
+	for name, newGenerator := range Generators {
+		a := newGenerator(rand.New(rand.NewSource(42)))
+		b := newGenerator(rand.New(rand.NewSource(42)))
+		for i := 0; i < 100; i++ {
+			if a.Next() != b.Next() {
+				t.Fatalf("%s: sequences diverge at %d", name, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package engine
+
+//-------------------------------------------------------------------------
+// GameMode
+//-------------------------------------------------------------------------
+
+// GameMode defines the goal of a game: how it starts, when it ends and what
+// happens along the way. Modes are stateless, whatever they need to keep
+// track of lives in the session (session snapshots must capture it).
+type GameMode interface {
+	// called by Reset after everything else is reset
+	Start(gs *GameSession)
+
+	// called after every update while the game is running (GS_Playing or
+	// GS_Clearing)
+	Update(gs *GameSession, delta uint32)
+
+	// called after a figure has locked and its lines have been counted
+	Locked(gs *GameSession, lines int)
+
+	// called when a new figure doesn't fit on the field, returns true if the
+	// mode made room for it and the game goes on
+	ToppedOut(gs *GameSession) bool
+
+	// whether the level goes up with cleared lines
+	LevelsUp() bool
+}
+
+// Known modes by name, the name is what goes into Config.Mode.
+var Modes = map[string]GameMode{
+	"marathon": MarathonMode{},
+	"sprint":   SprintMode{},
+	"ultra":    UltraMode{},
+	"dig":      DigMode{},
+	"survival": SurvivalMode{},
+	"zen":      ZenMode{},
+}
+
+//-------------------------------------------------------------------------
+// MarathonMode
+//-------------------------------------------------------------------------
+
+// Endless play, the level goes up every linesPerLevel lines until the game
+// is over.
+type MarathonMode struct{}
+
+func (MarathonMode) Start(gs *GameSession)                {}
+func (MarathonMode) Update(gs *GameSession, delta uint32) {}
+func (MarathonMode) Locked(gs *GameSession, lines int)    {}
+func (MarathonMode) ToppedOut(gs *GameSession) bool       { return false }
+func (MarathonMode) LevelsUp() bool                       { return true }
+
+//-------------------------------------------------------------------------
+// SprintMode
+//-------------------------------------------------------------------------
+
+// Clear Config.LineGoal lines as fast as possible, the level stays the same.
+type SprintMode struct{}
+
+func (SprintMode) Start(gs *GameSession)                {}
+func (SprintMode) Update(gs *GameSession, delta uint32) {}
+func (SprintMode) ToppedOut(gs *GameSession) bool       { return false }
+func (SprintMode) LevelsUp() bool                       { return false }
+
+func (SprintMode) Locked(gs *GameSession, lines int) {
+	if gs.Lines >= gs.config.LineGoal {
+		gs.finish()
+	}
+}
+
+//-------------------------------------------------------------------------
+// UltraMode
+//-------------------------------------------------------------------------
+
+// Score as much as possible in Config.TimeLimit, the level stays the same.
+type UltraMode struct{}
+
+func (UltraMode) Start(gs *GameSession)             {}
+func (UltraMode) Locked(gs *GameSession, lines int) {}
+func (UltraMode) ToppedOut(gs *GameSession) bool    { return false }
+func (UltraMode) LevelsUp() bool                    { return false }
+
+func (UltraMode) Update(gs *GameSession, delta uint32) {
+	if gs.PlayTime >= gs.config.TimeLimit {
+		gs.PlayTime = gs.config.TimeLimit
+		gs.finish()
+	}
+}
+
+// milliseconds left till the end of an ultra game
+func (self *GameSession) TimeLeft() uint32 {
+	if self.PlayTime >= self.config.TimeLimit {
+		return 0
+	}
+	return self.config.TimeLimit - self.PlayTime
+}
+
+//-------------------------------------------------------------------------
+// DigMode
+//-------------------------------------------------------------------------
+
+// The field starts with Config.GarbageRows rows of garbage, each with a
+// single random hole, the goal is to clear them all.
+type DigMode struct{}
+
+func (DigMode) Update(gs *GameSession, delta uint32) {}
+func (DigMode) ToppedOut(gs *GameSession) bool       { return false }
+func (DigMode) LevelsUp() bool                       { return false }
+
+func (DigMode) Start(gs *GameSession) {
+	for i := 0; i < gs.config.GarbageRows; i++ {
+		gs.Field.PushGarbage(gs.rand.Intn(gs.Field.Width))
+	}
+	gs.Garbage = gs.config.GarbageRows
+}
+
+func (DigMode) Locked(gs *GameSession, lines int) {
+	// complete rows are still on the field at this point
+	for _, y := range gs.Field.FullLines() {
+		if gs.Field.garbageRow(y) {
+			gs.Garbage--
+		}
+	}
+	if gs.Garbage == 0 {
+		gs.finish()
+	}
+}
+
+//-------------------------------------------------------------------------
+// SurvivalMode
+//-------------------------------------------------------------------------
+
+// how often garbage rises in survival mode: the first row comes after
+// survivalStartInterval, every next one survivalSpeedup sooner than the
+// previous one, but never sooner than survivalMinInterval (ms)
+const (
+	survivalStartInterval = 10000
+	survivalSpeedup       = 300
+	survivalMinInterval   = 1000
+)
+
+// Garbage rows rise from the bottom faster and faster, the game goes on
+// until the stack is pushed through the top.
+type SurvivalMode struct{}
+
+func (SurvivalMode) Start(gs *GameSession)             {}
+func (SurvivalMode) Locked(gs *GameSession, lines int) {}
+func (SurvivalMode) ToppedOut(gs *GameSession) bool    { return false }
+func (SurvivalMode) LevelsUp() bool                    { return false }
+
+func survivalInterval(rows int) uint32 {
+	if rows >= (survivalStartInterval-survivalMinInterval)/survivalSpeedup {
+		return survivalMinInterval
+	}
+	return survivalStartInterval - uint32(rows)*survivalSpeedup
+}
+
+func (SurvivalMode) Update(gs *GameSession, delta uint32) {
+	gs.garbageTime += delta
+	// rows waiting to collapse would move, wait till they are gone
+	for gs.State == GS_Playing && gs.garbageTime >= survivalInterval(gs.Garbage) {
+		gs.garbageTime -= survivalInterval(gs.Garbage)
+		gs.Garbage++
+		gs.pushGarbage()
+	}
+}
+
+// milliseconds till the next garbage row in survival mode
+func (self *GameSession) NextGarbage() uint32 {
+	interval := survivalInterval(self.Garbage)
+	if self.garbageTime >= interval {
+		return 0
+	}
+	return interval - self.garbageTime
+}
+
+//-------------------------------------------------------------------------
+// ZenMode
+//-------------------------------------------------------------------------
+
+// gravity level of zen mode, whatever Config.Level says
+const zenLevel = 1
+
+// Endless play at an easy pace without game over, when the stack reaches
+// the top the bottom half of the field is cleared.
+type ZenMode struct{}
+
+func (ZenMode) Update(gs *GameSession, delta uint32) {}
+func (ZenMode) Locked(gs *GameSession, lines int)    {}
+func (ZenMode) LevelsUp() bool                       { return false }
+
+func (ZenMode) Start(gs *GameSession) {
+	gs.Level = zenLevel
+}
+
+func (ZenMode) ToppedOut(gs *GameSession) bool {
+	var rows []int
+	for y := gs.Field.Height / 2; y < gs.Field.Height; y++ {
+		rows = append(rows, y)
+	}
+	for gs.Field.Collide(gs.Figure) {
+		gs.Field.RemoveLines(rows)
+	}
+	return true
+}
@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// bumped on every incompatible change of the replay format
+const ReplayVersion = 1
+
+//-------------------------------------------------------------------------
+// Replay
+//-------------------------------------------------------------------------
+
+// Everything needed to play a game again: the seed and the rules it was
+// played with and all the input GameSession got. Deltas of Update calls are
+// stored too, the same action a few milliseconds later may lead to a
+// different game.
+type Replay struct {
+	Version int
+	Seed    int64
+	Config  Config
+
+	// delta of every Update call, in order
+	Frames []uint32
+	Inputs []ReplayInput
+
+	// how the game ended, so that replays can be listed without playing
+	// them
+	Score int
+	Lines int
+	Level int
+
+	time uint32
+}
+
+type ReplayInput struct {
+	Frame   int    // how many Update calls were made before the input
+	Time    uint32 // milliseconds since the start of the game
+	Action  Action
+	Pressed bool // false if it's a release (see GameSession.ReleaseAction)
+}
+
+func NewReplay(seed int64, config *Config) *Replay {
+	return &Replay{Version: ReplayVersion, Seed: seed, Config: *config}
+}
+
+func (self *Replay) addFrame(delta uint32) {
+	self.Frames = append(self.Frames, delta)
+	self.time += delta
+}
+
+func (self *Replay) addInput(action Action, pressed bool) {
+	self.Inputs = append(self.Inputs, ReplayInput{
+		Frame:   len(self.Frames),
+		Time:    self.time,
+		Action:  action,
+		Pressed: pressed,
+	})
+}
+
+// writes the replay as JSON
+func (self *Replay) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(self)
+}
+
+// reads a replay written by Replay.Write
+func ReadReplay(r io.Reader) (*Replay, error) {
+	// settings added after the replay was made keep their defaults, that's
+	// how the game was played
+	replay := &Replay{Config: *DefaultConfig()}
+	if err := json.NewDecoder(r).Decode(replay); err != nil {
+		return nil, err
+	}
+	if replay.Version != ReplayVersion {
+		return nil, fmt.Errorf("unsupported replay version: %d (expected %d)",
+			replay.Version, ReplayVersion)
+	}
+	if err := replay.Config.Validate(); err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+//-------------------------------------------------------------------------
+// ReplayPlayer
+//-------------------------------------------------------------------------
+
+// how often (in frames) ReplayPlayer takes snapshots for seeking
+const replaySnapshotInterval = 500
+
+// Plays a replay back on a GameSession rebuilt from the replay's seed and
+// rules. Frames are played one by one, as the frontend sees fit, seeking
+// backwards restores the closest snapshot taken on the way and plays on from
+// there.
+type ReplayPlayer struct {
+	*GameSession
+	Replay *Replay
+
+	Frame int    // frames played so far
+	Time  uint32 // game time of the current frame, in milliseconds
+
+	input     int // next input to feed
+	held      [Pause + 1]bool
+	snapshots []*Snapshot // snapshots[i] is taken at frame i*replaySnapshotInterval
+	carry     uint32      // time passed to Advance not played yet
+}
+
+func NewReplayPlayer(replay *Replay) *ReplayPlayer {
+	p := &ReplayPlayer{Replay: replay}
+	p.GameSession = NewGameSession(&replay.Config, replay.Seed)
+	p.snapshots = append(p.snapshots, p.Snapshot())
+	return p
+}
+
+// total number of frames in the replay
+func (self *ReplayPlayer) Frames() int {
+	return len(self.Replay.Frames)
+}
+
+// whether the whole replay was played
+func (self *ReplayPlayer) Done() bool {
+	return self.Frame >= self.Frames()
+}
+
+func (self *ReplayPlayer) feedInputs() {
+	inputs := self.Replay.Inputs
+	for self.input < len(inputs) && inputs[self.input].Frame <= self.Frame {
+		in := &inputs[self.input]
+		if in.Pressed {
+			self.HandleAction(in.Action)
+		} else {
+			self.ReleaseAction(in.Action)
+		}
+		if in.Action >= 0 && int(in.Action) < len(self.held) {
+			self.held[in.Action] = in.Pressed
+		}
+		self.input++
+	}
+}
+
+// plays the next frame, returns false if there are no more frames
+func (self *ReplayPlayer) Step() bool {
+	if self.Done() {
+		return false
+	}
+	self.feedInputs()
+	delta := self.Replay.Frames[self.Frame]
+	self.Update(delta)
+	self.Frame++
+	self.Time += delta
+	if self.Done() {
+		// the input that ended the game comes after the last frame
+		self.feedInputs()
+	}
+	if self.Frame%replaySnapshotInterval == 0 &&
+		self.Frame/replaySnapshotInterval == len(self.snapshots) {
+		self.snapshots = append(self.snapshots, self.Snapshot())
+	}
+	return true
+}
+
+// Plays frames worth ms milliseconds of game time. Frames which don't fit
+// wholly into it wait for the next call.
+func (self *ReplayPlayer) Advance(ms uint32) {
+	self.carry += ms
+	for !self.Done() && self.Replay.Frames[self.Frame] <= self.carry {
+		self.carry -= self.Replay.Frames[self.Frame]
+		self.Step()
+	}
+	if self.Done() {
+		self.carry = 0
+	}
+}
+
+// jumps to the given frame, it's clamped to the replay's length
+func (self *ReplayPlayer) Seek(frame int) {
+	if frame < 0 {
+		frame = 0
+	}
+	if frame > self.Frames() {
+		frame = self.Frames()
+	}
+	if frame < self.Frame {
+		i := frame / replaySnapshotInterval
+		if i >= len(self.snapshots) {
+			i = len(self.snapshots) - 1
+		}
+		self.Restore(self.snapshots[i])
+		self.Frame = i * replaySnapshotInterval
+		self.Time = 0
+		for _, delta := range self.Replay.Frames[:self.Frame] {
+			self.Time += delta
+		}
+		self.input = 0
+		self.held = [len(self.held)]bool{}
+		self.feedHeld()
+	}
+	for self.Frame < frame {
+		self.Step()
+	}
+	self.carry = 0
+}
+
+// after restoring a snapshot: skips inputs fed before it and rebuilds the
+// set of held actions
+func (self *ReplayPlayer) feedHeld() {
+	inputs := self.Replay.Inputs
+	for self.input < len(inputs) && inputs[self.input].Frame < self.Frame {
+		in := &inputs[self.input]
+		if in.Action >= 0 && int(in.Action) < len(self.held) {
+			self.held[in.Action] = in.Pressed
+		}
+		self.input++
+	}
+}
+
+// jumps to the first frame at or after the given game time (ms)
+func (self *ReplayPlayer) SeekTime(ms uint32) {
+	frame, t := 0, uint32(0)
+	for frame < self.Frames() && t < ms {
+		t += self.Replay.Frames[frame]
+		frame++
+	}
+	self.Seek(frame)
+}
+
+// actions which are held down at the moment (see GameSession.ReleaseAction)
+// and the last one pressed
+func (self *ReplayPlayer) Input() (held []Action, last *ReplayInput) {
+	for action, pressed := range self.held {
+		if pressed {
+			held = append(held, Action(action))
+		}
+	}
+	for i := self.input - 1; i >= 0; i-- {
+		if self.Replay.Inputs[i].Pressed {
+			last = &self.Replay.Inputs[i]
+			break
+		}
+	}
+	return held, last
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// plays a game with random input until it's over and returns its replay
+func recordRandomGame(t *testing.T, config *Config, seed int64) *Replay {
+	gs := NewGameSession(config, seed)
+	gs.StartRecording()
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; !gs.Ended(); i++ {
+		if i > 1000000 {
+			t.Fatal("the game doesn't end")
+		}
+		switch n := r.Intn(400); {
+		case n == 0:
+			gs.HandleAction(HardDrop)
+		case n == 1:
+			gs.HandleAction(Hold)
+		case n < 20:
+			// movement and soft drop, sometimes held for a while
+			action := Action(r.Intn(int(SoftDrop) + 1))
+			gs.HandleAction(action)
+			if r.Intn(3) != 0 {
+				gs.ReleaseAction(action)
+			}
+		}
+		gs.Update(uint32(r.Intn(20)))
+	}
+
+	// through JSON, to make sure nothing is lost on the way
+	var buf bytes.Buffer
+	if err := gs.Replay.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := ReadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Score != gs.Score || replay.Lines != gs.Lines {
+		t.Fatalf("replay results: %d/%d, the game ended with %d/%d",
+			replay.Score, replay.Lines, gs.Score, gs.Lines)
+	}
+	return replay
+}
+
+func sameState(a, b *GameSession) bool {
+	return a.Score == b.Score && a.Lines == b.Lines && a.State == b.State &&
+		reflect.DeepEqual(a.Field.Blocks, b.Field.Blocks) &&
+		*a.Figure == *b.Figure && *a.Queue[0] == *b.Queue[0]
+}
+
+func TestReplayPlayback(t *testing.T) {
+	config := DefaultConfig()
+	config.Randomizer = "bag"
+	config.Rotation = "srs"
+	replay := recordRandomGame(t, config, 7)
+
+	p := NewReplayPlayer(replay)
+	for p.Step() {
+	}
+	if p.State != GS_GameOver || p.Score != replay.Score || p.Lines != replay.Lines {
+		t.Fatalf("playback ended with state %d, score %d, lines %d; want game over, %d, %d",
+			p.State, p.Score, p.Lines, replay.Score, replay.Lines)
+	}
+}
+
+func TestReplaySeek(t *testing.T) {
+	replay := recordRandomGame(t, DefaultConfig(), 3)
+	if len(replay.Frames) < 3*replaySnapshotInterval {
+		t.Fatalf("the game is too short: %d frames", len(replay.Frames))
+	}
+
+	p := NewReplayPlayer(replay)
+	p.Seek(p.Frames())
+	for _, frame := range []int{
+		replaySnapshotInterval*2 + 17,
+		replaySnapshotInterval,
+		5,
+		replaySnapshotInterval*3 - 1,
+		0,
+	} {
+		p.Seek(frame)
+
+		want := NewReplayPlayer(replay)
+		for want.Frame < frame {
+			want.Step()
+		}
+		if p.Frame != frame || p.Time != want.Time || !sameState(p.GameSession, want.GameSession) {
+			t.Fatalf("state after seeking to frame %d differs from playing up to it", frame)
+		}
+	}
+}
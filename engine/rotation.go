@@ -0,0 +1,217 @@
+package engine
+
+//-------------------------------------------------------------------------
+// RotationSystem
+//-------------------------------------------------------------------------
+
+// RotationSystem defines how figures look when they spawn and how they
+// rotate on the field.
+type RotationSystem interface {
+	NewFigure(class uint32) *TetrisFigure
+
+	// Rotates the figure (possibly moving it) and returns the index of the
+	// kick used (0 means no kick) and true, if the rotation is not possible
+	// leaves the figure as is and returns false.
+	Rotate(field *TetrisField, figure *TetrisFigure, clockwise bool) (int, bool)
+}
+
+// Known rotation systems by name, the name is what goes into
+// Config.Rotation.
+var RotationSystems = map[string]RotationSystem{
+	"classic": ClassicRotation{},
+	"srs":     SRS{},
+}
+
+// Orientation states, kept in TetrisFigure.Rotation
+const (
+	RotationSpawn = iota
+	RotationRight
+	RotationTwo
+	RotationLeft
+)
+
+func nextRotation(rotation int, clockwise bool) int {
+	if clockwise {
+		return (rotation + 1) % 4
+	}
+	return (rotation + 3) % 4
+}
+
+//-------------------------------------------------------------------------
+// ClassicRotation
+//-------------------------------------------------------------------------
+
+// The original gotris rotation: figures rotate around their '2' block and
+// don't rotate at all if the result collides with something.
+type ClassicRotation struct{}
+
+func (ClassicRotation) NewFigure(class uint32) *TetrisFigure {
+	return NewTetrisFigureOfClass(class)
+}
+
+func (ClassicRotation) Rotate(field *TetrisField, figure *TetrisFigure, clockwise bool) (int, bool) {
+	forward, backward := rotateCWBlock, rotateCCWBlock
+	if !clockwise {
+		forward, backward = backward, forward
+	}
+
+	figure.Rotate(forward)
+	if field.Collide(figure) {
+		figure.Rotate(backward)
+		return 0, false
+	}
+	figure.Rotation = nextRotation(figure.Rotation, clockwise)
+	return 0, true
+}
+
+//-------------------------------------------------------------------------
+// SRS
+//-------------------------------------------------------------------------
+
+// Spawn orientations of the Super Rotation System, in the same order as
+// specs. Three blocks wide figures live in the top left 3x3 part of the
+// grid and rotate within it, I rotates within the whole 4x4 grid and O
+// doesn't rotate at all.
+var srsSpecs = [...]string{
+	`
+0110
+1200
+0000
+0000
+`,
+	`
+1100
+0210
+0000
+0000
+`,
+	`
+0100
+1210
+0000
+0000
+`,
+	`
+0000
+1111
+0000
+0000
+`,
+	`
+0110
+0110
+0000
+0000
+`,
+	`
+0010
+1210
+0000
+0000
+`,
+	`
+1000
+1210
+0000
+0000
+`,
+}
+
+type srsKick struct {
+	X, Y int
+}
+
+// Kick tables indexed by [from rotation][0 - clockwise, 1 - counter
+// clockwise]. Y axis points up here like in every SRS description, don't
+// forget to flip it.
+var srsKicksJLSTZ = [4][2][5]srsKick{
+	RotationSpawn: {
+		{{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}}, // 0 -> R
+		{{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},    // 0 -> L
+	},
+	RotationRight: {
+		{{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}}, // R -> 2
+		{{0, 0}, {1, 0}, {1, -1}, {0, 2}, {1, 2}}, // R -> 0
+	},
+	RotationTwo: {
+		{{0, 0}, {1, 0}, {1, 1}, {0, -2}, {1, -2}},    // 2 -> L
+		{{0, 0}, {-1, 0}, {-1, 1}, {0, -2}, {-1, -2}}, // 2 -> R
+	},
+	RotationLeft: {
+		{{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}}, // L -> 0
+		{{0, 0}, {-1, 0}, {-1, -1}, {0, 2}, {-1, 2}}, // L -> 2
+	},
+}
+
+var srsKicksI = [4][2][5]srsKick{
+	RotationSpawn: {
+		{{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}}, // 0 -> R
+		{{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}}, // 0 -> L
+	},
+	RotationRight: {
+		{{0, 0}, {-1, 0}, {2, 0}, {-1, 2}, {2, -1}}, // R -> 2
+		{{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}}, // R -> 0
+	},
+	RotationTwo: {
+		{{0, 0}, {2, 0}, {-1, 0}, {2, 1}, {-1, -2}}, // 2 -> L
+		{{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}}, // 2 -> R
+	},
+	RotationLeft: {
+		{{0, 0}, {1, 0}, {-2, 0}, {1, -2}, {-2, 1}}, // L -> 0
+		{{0, 0}, {-2, 0}, {1, 0}, {-2, -1}, {1, 2}}, // L -> 2
+	},
+}
+
+// Super Rotation System, the one used by modern tetris games.
+type SRS struct{}
+
+func (SRS) NewFigure(class uint32) *TetrisFigure {
+	f := NewTetrisFigure(srsSpecs[class], specColors[class])
+	f.Class = class
+	return f
+}
+
+func (SRS) Rotate(field *TetrisField, figure *TetrisFigure, clockwise bool) (int, bool) {
+	if figure.Class == classO {
+		return 0, true
+	}
+
+	size := 3
+	kicks := &srsKicksJLSTZ
+	if figure.Class == classI {
+		size = 4
+		kicks = &srsKicksI
+	}
+
+	var blocks [16]TetrisBlock
+	for i := 0; i < 16; i++ {
+		if !figure.Blocks[i].Filled {
+			continue
+		}
+		x, y := i%4, i/4
+		if clockwise {
+			x, y = size-1-y, x
+		} else {
+			x, y = y, size-1-x
+		}
+		blocks[y*4+x] = figure.Blocks[i]
+	}
+
+	rotated := *figure
+	rotated.Blocks = blocks
+	rotated.Rotation = nextRotation(figure.Rotation, clockwise)
+
+	dir := 0
+	if !clockwise {
+		dir = 1
+	}
+	for i, kick := range kicks[figure.Rotation][dir] {
+		rotated.X = figure.X + kick.X
+		rotated.Y = figure.Y - kick.Y
+		if !field.Collide(&rotated) {
+			*figure = rotated
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package engine
+
+import "testing"
+
+// builds a field from rows of '.' (empty) and '#' (filled), top to bottom
+func testField(rows ...string) *TetrisField {
+	field := NewTetrisField(len(rows[0]), len(rows))
+	for y, row := range rows {
+		for x, c := range row {
+			field.Blocks[y*field.Width+x].Filled = c == '#'
+		}
+	}
+	return field
+}
+
+// an SRS figure of the class turned to the given orientation
+func srsFigure(class uint32, rotation int) *TetrisFigure {
+	field := NewTetrisField(10, 10)
+	f := SRS{}.NewFigure(class)
+	f.X, f.Y = 3, 3
+	for f.Rotation != rotation {
+		SRS{}.Rotate(field, f, true)
+	}
+	return f
+}
+
+func TestSRSWallKicks(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		class     uint32
+		from      int
+		x, y      int
+		clockwise bool
+		kick      int
+		wantX     int
+	}{
+		// kicks[R][ccw][1] is (+1, 0)
+		{"T R->0 off the left wall", classT, RotationRight, -1, 2, false, 1, 0},
+		// kicks[R][ccw][1] is (+2, 0) into the wall, [2] is (-1, 0)
+		{"I R->0 off the right wall", classI, RotationRight, 7, 2, false, 2, 6},
+		// kicks[L][cw][1] is (+1, 0)
+		{"I L->0 off the left wall", classI, RotationLeft, -1, 2, true, 1, 0},
+	} {
+		field := NewTetrisField(10, 8)
+		f := srsFigure(c.class, c.from)
+		f.X, f.Y = c.x, c.y
+		if field.Collide(f) {
+			t.Fatalf("%s: the figure doesn't fit where it starts", c.name)
+		}
+		kick, ok := SRS{}.Rotate(field, f, c.clockwise)
+		if !ok || kick != c.kick || f.X != c.wantX || f.Y != c.y {
+			t.Errorf("%s: rotated %v with kick %d to %d,%d; want kick %d to %d,%d",
+				c.name, ok, kick, f.X, f.Y, c.kick, c.wantX, c.y)
+		}
+		if f.Rotation != RotationSpawn || field.Collide(f) {
+			t.Errorf("%s: ended in orientation %d, colliding: %v", c.name, f.Rotation, field.Collide(f))
+		}
+	}
+}
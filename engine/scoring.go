@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+)
+
+//-------------------------------------------------------------------------
+// ClearEvent
+//-------------------------------------------------------------------------
+
+// Line clear types, the value is the number of lines cleared
+const (
+	ClearSingle = iota + 1
+	ClearDouble
+	ClearTriple
+	ClearTetris
+)
+
+var clearNames = [...]string{
+	ClearSingle: "Single",
+	ClearDouble: "Double",
+	ClearTriple: "Triple",
+	ClearTetris: "Tetris",
+}
+
+// base points of each line clear type, multiplied by level
+var clearPoints = [...]int{
+	ClearSingle: 100,
+	ClearDouble: 300,
+	ClearTriple: 500,
+	ClearTetris: 800,
+}
+
+// base points of T-spins indexed by [T-spin type][lines]
+var tspinPoints = [...][4]int{
+	TSpinMini: {100, 200, 400, 0},
+	TSpinFull: {400, 800, 1200, 1600},
+}
+
+const comboPoints = 50
+
+// Describes a single scoring event, what the player did and what it was
+// worth.
+type ClearEvent struct {
+	Lines      int // may be 0 for T-spins
+	TSpin      int // TSpinNone, TSpinMini or TSpinFull
+	BackToBack bool
+	Combo      int // 0 for the first clear in a row, 1 for the next one, ...
+	Points     int
+}
+
+// human readable description, e.g. "Back-to-Back T-Spin Double, Combo 2"
+func (self *ClearEvent) String() string {
+	s := clearNames[self.Lines]
+	switch self.TSpin {
+	case TSpinMini:
+		s = "Mini T-Spin " + s
+	case TSpinFull:
+		s = "T-Spin " + s
+	}
+	s = strings.TrimSpace(s)
+	if self.BackToBack {
+		s = "Back-to-Back " + s
+	}
+	if self.Combo > 0 {
+		s += ", Combo " + strconv.Itoa(self.Combo)
+	}
+	return s
+}
+
+//-------------------------------------------------------------------------
+// Scoring
+//-------------------------------------------------------------------------
+
+// Guideline scoring: standard line clear and T-spin values, back-to-back
+// bonus for consecutive difficult clears (tetrises and T-spins that clear
+// lines) and combos for consecutive clearing figures.
+type Scoring struct {
+	backToBack bool // the last clear was a difficult one
+	combo      int  // -1 when not in a combo
+}
+
+func NewScoring() *Scoring {
+	return &Scoring{combo: -1}
+}
+
+// Must be called every time a figure locks, tspin is what DetectTSpin said
+// about it. Returns the scoring event and true if the figure cleared
+// anything or was a T-spin.
+func (self *Scoring) Lock(lines, level, tspin int) (ClearEvent, bool) {
+	if lines == 0 {
+		self.combo = -1
+		if tspin == TSpinNone {
+			return ClearEvent{}, false
+		}
+		// T-spin zero, doesn't affect back-to-back
+		e := ClearEvent{TSpin: tspin}
+		e.Points = tspinPoints[tspin][0] * level
+		return e, true
+	}
+
+	self.combo++
+	e := ClearEvent{Lines: lines, TSpin: tspin, Combo: self.combo}
+	if tspin != TSpinNone {
+		e.Points = tspinPoints[tspin][lines] * level
+	} else {
+		e.Points = clearPoints[lines] * level
+	}
+
+	difficult := lines == ClearTetris || tspin != TSpinNone
+	if difficult && self.backToBack {
+		e.BackToBack = true
+		e.Points += e.Points / 2
+	}
+	self.backToBack = difficult
+
+	e.Points += comboPoints * self.combo * level
+	return e, true
+}
@@ -0,0 +1,41 @@
+package engine
+
+import "testing"
+
+func TestScoringTotals(t *testing.T) {
+	type lock struct{ lines, tspin int }
+	none := lock{0, TSpinNone}
+	single := lock{ClearSingle, TSpinNone}
+	tetris := lock{ClearTetris, TSpinNone}
+	tsd := lock{ClearDouble, TSpinFull}
+
+	for _, c := range []struct {
+		name  string
+		level int
+		locks []lock
+		want  int
+	}{
+		{"back-to-back tetris", 1, []lock{tetris, none, tetris}, 800 + 1200},
+		{"single breaks back-to-back", 1, []lock{tetris, none, single, none, tetris}, 800 + 100 + 800},
+		{"T-spin keeps back-to-back", 1, []lock{tsd, none, tetris, none, tsd}, 1200 + 1200 + 1800},
+		// T-spins without lines neither break nor start back-to-back
+		{"T-spin zero", 1, []lock{tetris, {0, TSpinFull}, tetris}, 800 + 400 + 1200},
+		{"mini T-spin single", 1, []lock{{ClearSingle, TSpinMini}}, 200},
+		// combo bonus: 50 per combo step and level
+		{"combo", 2, []lock{single, single, single, none, single}, 200 + 300 + 400 + 200},
+		{"back-to-back combo", 1, []lock{tetris, tetris, tsd}, 800 + 1250 + 1900},
+	} {
+		scoring := NewScoring()
+		total := 0
+		for _, l := range c.locks {
+			e, ok := scoring.Lock(l.lines, c.level, l.tspin)
+			if ok != (l != none) {
+				t.Errorf("%s: Lock(%d, %d) returned %v", c.name, l.lines, l.tspin, ok)
+			}
+			total += e.Points
+		}
+		if total != c.want {
+			t.Errorf("%s: %d points, want %d", c.name, total, c.want)
+		}
+	}
+}
@@ -0,0 +1,688 @@
+// Package engine implements gotris game rules without any dependencies on
+// SDL or OpenGL, so that it can be driven by any frontend.
+package engine
+
+import (
+	"math/rand"
+)
+
+const grayifyingInterval = 100
+
+const MaxLevel = 20
+
+// lines to clear to advance a level
+const linesPerLevel = 10
+
+// Gravity curve, milliseconds it takes a figure to fall one row at each
+// level. Follows the guideline formula (0.8-(level-1)*0.007)^(level-1)
+// seconds, 0 means 20G - figures fall to the bottom instantly.
+var gravity = [MaxLevel + 1]uint32{
+	0, // there is no level 0
+	1000, 793, 618, 473, 355, 262, 190, 135, 94, 64,
+	43, 28, 18, 11, 7, 4, 3, 1, 1, 0,
+}
+
+// points per cell for dropping a figure manually
+const (
+	softDropPoints = 1
+	hardDropPoints = 2
+)
+
+//-------------------------------------------------------------------------
+// GameSession
+//-------------------------------------------------------------------------
+
+// Game state
+const (
+	GS_Playing = iota
+	GS_Paused
+	GS_GameOver
+	GS_Clearing // complete lines are shown for a while before they collapse
+	GS_Finished // the goal of the mode was reached
+)
+
+type GameSession struct {
+	Field      *TetrisField
+	Figure     *TetrisFigure
+	Queue      []*TetrisFigure // upcoming figures, Queue[0] is the next one
+	HoldFigure *TetrisFigure   // nil if nothing is held
+
+	Score int
+	Level int
+	Lines int // total lines cleared
+	State int
+
+	// statistics of the current game
+	PlayTime      uint32 // milliseconds, pauses don't count
+	Pieces        int    // figures locked
+	FinesseFaults int    // figures placed with more inputs than needed
+	Garbage       int    // dig: garbage rows left to clear, survival: rows received
+
+	// complete rows waiting to collapse while in GS_Clearing state
+	ClearingRows []int
+
+	// called for every line clear, frontends use it to show what happened
+	OnClear func(e ClearEvent)
+
+	// called once the game ends, State tells whether it's over or finished
+	OnEnd func()
+
+	// if not nil, all the input and updates of the game are recorded into
+	// it, see StartRecording
+	Replay *Replay
+
+	// the piece sequence is fully determined by the seed, Reset uses it
+	// as well, so change it before calling Reset to get a different game
+	Seed int64
+
+	config         Config
+	rand           *rand.Rand
+	source         *countingSource // rand's source
+	generator      Generator
+	mode           GameMode
+	scoring        *Scoring
+	rotation       RotationSystem
+	time           uint32
+	clearingTime   uint32
+	grayifyingTime uint32
+	holdUsed       bool
+	pieceInputs    int    // moves and rotations of the current figure, for finesse
+	garbageTime    uint32 // since the last garbage row in survival mode
+	pausedState    int    // the state to go back to when the pause ends
+
+	// held actions, they outlive figures: DAS stays charged and soft drop
+	// keeps working for the next figure
+	softDrop  bool
+	leftHeld  bool
+	rightHeld bool
+	shiftDir  int    // -1 (left), 1 (right) or 0
+	dasTime   uint32 // how long shiftDir has been held
+	arrTime   uint32
+
+	// lock delay state of the current figure
+	grounded   bool
+	lockTime   uint32
+	lockResets int
+	lowestY    int
+
+	// whether the last successful movement of the figure was a rotation
+	// and which kick it used, for T-spin detection
+	lastRotation bool
+	lastKick     int
+}
+
+// config is expected to be valid (see Config.Validate)
+func NewGameSession(config *Config, seed int64) *GameSession {
+	gs := new(GameSession)
+	gs.config = *config
+	if gs.config.Level > MaxLevel {
+		gs.config.Level = MaxLevel
+	}
+	if gs.config.Level < 1 {
+		gs.config.Level = 1
+	}
+
+	gs.Field = NewTetrisField(gs.config.Width, gs.config.Height)
+	gs.Seed = seed
+	gs.source = newCountingSource(seed)
+	gs.rand = rand.New(gs.source)
+	gs.rotation = RotationSystems[gs.config.Rotation]
+	gs.mode = Modes[gs.config.Mode]
+	gs.Reset()
+	return gs
+}
+
+func (self *GameSession) Reset() {
+	self.Field.Clear()
+	self.rand.Seed(self.Seed)
+	self.generator = Generators[self.config.Randomizer](self.rand)
+	self.scoring = NewScoring()
+	self.Figure = self.newFigure()
+	self.Queue = self.Queue[:0]
+	for i := 0; i < self.config.Previews; i++ {
+		self.Queue = append(self.Queue, self.newFigure())
+	}
+	self.resetLock()
+	self.HoldFigure = nil
+	self.holdUsed = false
+	self.softDrop = false
+	self.leftHeld = false
+	self.rightHeld = false
+	self.shiftDir = 0
+	self.Score = 0
+	self.Level = self.config.Level
+	self.Lines = 0
+	self.State = GS_Playing
+	self.ClearingRows = nil
+	self.time = 0
+	self.clearingTime = 0
+	self.grayifyingTime = 0
+	self.PlayTime = 0
+	self.Pieces = 0
+	self.FinesseFaults = 0
+	self.pieceInputs = 0
+	self.Garbage = 0
+	self.garbageTime = 0
+	self.mode.Start(self)
+	if self.Replay != nil {
+		self.StartRecording()
+	}
+}
+
+// Starts recording a new replay, must be called before the first Update.
+// Reset starts a new one as well once recording was enabled. Input is
+// recorded between the updates it came in, so HandleAction, ReleaseAction
+// and Update must not be called concurrently.
+func (self *GameSession) StartRecording() {
+	self.Replay = NewReplay(self.Seed, &self.config)
+}
+
+func (self *GameSession) newFigure() *TetrisFigure {
+	return spawnFigure(self.rotation, self.generator.Next(), self.Field.Width)
+}
+
+// a new figure of the class centred horizontally on a field of the given
+// width (leaning to the left if it can't be centred exactly)
+func spawnFigure(rotation RotationSystem, class uint32, width int) *TetrisFigure {
+	figure := rotation.NewFigure(class)
+	minCol, cols := figureSpan(figure)
+	figure.X = (width-cols)/2 - minCol
+	return figure
+}
+
+// the leftmost filled column of the figure's grid and how many columns are
+// filled
+func figureSpan(figure *TetrisFigure) (int, int) {
+	minCol, maxCol := 4, -1
+	for i, b := range figure.Blocks {
+		if !b.Filled {
+			continue
+		}
+		if i%4 < minCol {
+			minCol = i % 4
+		}
+		if i%4 > maxCol {
+			maxCol = i % 4
+		}
+	}
+	return minCol, maxCol - minCol + 1
+}
+
+// takes the first figure out of the queue and refills it
+func (self *GameSession) popQueue() *TetrisFigure {
+	figure := self.Queue[0]
+	copy(self.Queue, self.Queue[1:])
+	self.Queue[len(self.Queue)-1] = self.newFigure()
+	return figure
+}
+
+// makes the figure current, if there is no room for it the game is over
+func (self *GameSession) spawn(figure *TetrisFigure) {
+	self.Figure = figure
+	self.pieceInputs = 0
+	self.resetLock()
+	if self.Field.Collide(self.Figure) && !self.mode.ToppedOut(self) {
+		self.end(GS_GameOver)
+	}
+}
+
+// ends the game with the state given (GS_GameOver or GS_Finished)
+func (self *GameSession) end(state int) {
+	if self.State == GS_Clearing {
+		// the lines were counted already, don't leave them hanging
+		self.Field.RemoveLines(self.ClearingRows)
+		self.ClearingRows = nil
+	}
+	self.State = state
+	if self.Replay != nil {
+		self.Replay.Score = self.Score
+		self.Replay.Lines = self.Lines
+		self.Replay.Level = self.Level
+	}
+	if self.OnEnd != nil {
+		self.OnEnd()
+	}
+}
+
+// the goal of the mode was reached
+func (self *GameSession) finish() {
+	self.end(GS_Finished)
+}
+
+// Pushes a garbage row with a random hole up from the bottom. The current
+// figure goes up with the field if the new row gets in its way, the game is
+// over when either the stack or the figure is pushed through the top.
+func (self *GameSession) pushGarbage() {
+	if !self.Field.PushGarbage(self.rand.Intn(self.Field.Width)) {
+		self.end(GS_GameOver)
+		return
+	}
+	if self.Field.Collide(self.Figure) {
+		self.Figure.Y--
+		self.lowestY--
+		if self.Field.Collide(self.Figure) {
+			self.end(GS_GameOver)
+			return
+		}
+	}
+	self.grounded = self.Field.DropDistance(self.Figure) == 0
+}
+
+// whether the game is over or finished
+func (self *GameSession) Ended() bool {
+	return self.State == GS_GameOver || self.State == GS_Finished
+}
+
+// whether the input and updates should go into the replay, nothing that
+// happens after the game has ended matters
+func (self *GameSession) recording() bool {
+	return self.Replay != nil && !self.Ended()
+}
+
+func (self *GameSession) resetLock() {
+	self.grounded = false
+	self.lockTime = 0
+	self.lockResets = 0
+	self.lowestY = self.Figure.Y
+	self.lastRotation = false
+}
+
+// must be called after the figure was successfully moved or rotated, while
+// on the ground it restarts the lock delay (a limited number of times)
+func (self *GameSession) moved() {
+	if self.Figure.Y > self.lowestY {
+		// reached new depths, the player gets a fresh set of resets
+		self.lowestY = self.Figure.Y
+		self.lockResets = 0
+		self.lockTime = 0
+	}
+	if self.grounded && self.lockResets < self.config.LockResets {
+		self.lockResets++
+		self.lockTime = 0
+	}
+	self.grounded = self.Field.DropDistance(self.Figure) == 0
+	self.lastRotation = false
+}
+
+func (self *GameSession) rotate(clockwise bool) {
+	kick, ok := self.rotation.Rotate(self.Field, self.Figure, clockwise)
+	if !ok {
+		return
+	}
+	self.moved()
+	self.lastRotation = true
+	self.lastKick = kick
+}
+
+// merges the figure into the field and spawns the next figure, if there
+// are complete lines it goes to GS_Clearing first
+func (self *GameSession) lock() {
+	tspin := TSpinNone
+	if self.lastRotation {
+		tspin = DetectTSpin(self.Field, self.Figure, self.lastKick)
+	}
+	minInputs := FinesseInputs(self.rotation, self.Field.Width, self.Field.Height, self.Figure)
+	if minInputs >= 0 && self.pieceInputs > minInputs {
+		self.FinesseFaults++
+	}
+	self.Pieces++
+	self.Field.Merge(self.Figure)
+	rows := self.Field.FullLines()
+	if e, ok := self.scoring.Lock(len(rows), self.Level, tspin); ok {
+		self.AddScore(e.Points)
+		if self.OnClear != nil {
+			self.OnClear(e)
+		}
+	}
+	self.addLines(len(rows))
+	self.mode.Locked(self, len(rows))
+	if self.Ended() {
+		self.Field.RemoveLines(rows)
+		return
+	}
+
+	if len(rows) > 0 && self.config.LineClearDelay > 0 {
+		self.State = GS_Clearing
+		self.ClearingRows = rows
+		self.clearingTime = 0
+		return
+	}
+	self.Field.RemoveLines(rows)
+	self.holdUsed = false
+	self.spawn(self.popQueue())
+}
+
+// how far the line clear animation is, from 0 to 1
+func (self *GameSession) ClearingProgress() float32 {
+	if self.config.LineClearDelay == 0 {
+		return 1
+	}
+	return float32(self.clearingTime) / float32(self.config.LineClearDelay)
+}
+
+// a copy of the current figure at the position where a hard drop would put
+// it
+func (self *GameSession) GhostFigure() *TetrisFigure {
+	ghost := *self.Figure
+	ghost.Y += self.Field.DropDistance(self.Figure)
+	return &ghost
+}
+
+// whether the Hold action is available for the current figure
+func (self *GameSession) CanHold() bool {
+	return !self.holdUsed
+}
+
+func (self *GameSession) hold() {
+	if self.holdUsed {
+		return
+	}
+	self.holdUsed = true
+
+	held := self.HoldFigure
+	self.HoldFigure = spawnFigure(self.rotation, self.Figure.Class, self.Field.Width)
+	if held == nil {
+		self.spawn(self.popQueue())
+	} else {
+		self.spawn(held)
+	}
+}
+
+// milliseconds per row at the current level, 0 means 20G
+func (self *GameSession) Speed() uint32 {
+	return gravity[self.Level]
+}
+
+// gravity interval in effect, takes soft drop into account
+func (self *GameSession) fallInterval() uint32 {
+	if !self.softDrop {
+		return self.Speed()
+	}
+	interval := self.Speed() / uint32(self.config.SoftDropFactor)
+	if interval == 0 && self.Speed() != 0 {
+		interval = 1
+	}
+	return interval
+}
+
+// the rules the session was made with
+func (self *GameSession) Config() Config {
+	return self.config
+}
+
+// pieces locked per second of play time
+func (self *GameSession) PiecesPerSecond() float64 {
+	if self.PlayTime == 0 {
+		return 0
+	}
+	return float64(self.Pieces) * 1000 / float64(self.PlayTime)
+}
+
+func (self *GameSession) AddScore(score int) {
+	self.Score += score
+}
+
+// counts cleared lines, every linesPerLevel lines advance the level
+func (self *GameSession) addLines(lines int) {
+	self.Lines += lines
+	if !self.mode.LevelsUp() {
+		return
+	}
+	self.Level = self.config.Level + self.Lines/linesPerLevel
+	if self.Level > MaxLevel {
+		self.Level = MaxLevel
+	}
+}
+
+//-------------------------------------------------------------------------
+// GameSession::Update
+//-------------------------------------------------------------------------
+
+// moves the figure one block sideways, returns false if it's blocked
+func (self *GameSession) shift(dir int) bool {
+	self.Figure.X += dir
+	if self.Field.Collide(self.Figure) {
+		self.Figure.X -= dir
+		return false
+	}
+	self.moved()
+	return true
+}
+
+// Delayed auto shift: holding left or right moves the figure once, then
+// again once DAS milliseconds have passed and every ARR milliseconds after
+// that (ARR of 0 moves it to the wall at once). The delay keeps charging
+// when there is no figure to move.
+func (self *GameSession) autoShift(delta uint32) {
+	if self.shiftDir == 0 {
+		return
+	}
+	if self.dasTime < self.config.DAS {
+		self.dasTime += delta
+		if self.dasTime < self.config.DAS {
+			return
+		}
+		delta = self.dasTime - self.config.DAS
+		self.dasTime = self.config.DAS
+		// the first automatic move happens right when DAS expires
+		self.arrTime = self.config.ARR
+	}
+	if self.State != GS_Playing {
+		return
+	}
+
+	if self.config.ARR == 0 {
+		for self.shift(self.shiftDir) {
+		}
+		return
+	}
+	self.arrTime += delta
+	for self.arrTime >= self.config.ARR {
+		self.arrTime -= self.config.ARR
+		self.shift(self.shiftDir)
+	}
+}
+
+func (self *GameSession) updatePlaying(delta uint32) {
+	self.autoShift(delta)
+	self.time += delta
+	self.grayifyingTime += delta
+	if self.grayifyingTime > grayifyingInterval {
+		self.grayifyingTime -= grayifyingInterval
+		self.Field.Grayify()
+	}
+	interval := self.fallInterval()
+	if interval == 0 {
+		self.time = 0
+		if distance := self.Field.DropDistance(self.Figure); distance > 0 {
+			self.Figure.Y += distance
+			self.moved()
+		}
+	}
+	for interval > 0 && self.time > interval {
+		self.time -= interval
+		if self.Field.DropDistance(self.Figure) > 0 {
+			self.Figure.Y++
+			if self.softDrop {
+				self.AddScore(softDropPoints)
+			}
+			self.moved()
+		}
+	}
+
+	self.grounded = self.Field.DropDistance(self.Figure) == 0
+	if !self.grounded {
+		return
+	}
+	self.lockTime += delta
+	if self.lockTime >= self.config.LockDelay {
+		self.lock()
+	}
+}
+
+func (self *GameSession) updateClearing(delta uint32) {
+	self.autoShift(delta)
+	self.clearingTime += delta
+	if self.clearingTime < self.config.LineClearDelay {
+		return
+	}
+
+	self.Field.RemoveLines(self.ClearingRows)
+	self.ClearingRows = nil
+	self.State = GS_Playing
+	self.holdUsed = false
+	self.spawn(self.popQueue())
+}
+
+func (self *GameSession) updateGameOver(delta uint32) {
+	self.grayifyingTime += delta
+	if self.grayifyingTime > grayifyingInterval {
+		self.grayifyingTime -= grayifyingInterval
+		self.Field.Grayify()
+	}
+}
+
+func (self *GameSession) updateGamePaused(delta uint32) {
+	self.updateGameOver(delta)
+}
+
+func (self *GameSession) Update(delta uint32) {
+	if self.recording() {
+		self.Replay.addFrame(delta)
+	}
+	switch self.State {
+	case GS_Playing:
+		self.PlayTime += delta
+		self.updatePlaying(delta)
+	case GS_GameOver, GS_Finished:
+		self.updateGameOver(delta)
+	case GS_Paused:
+		self.updateGamePaused(delta)
+	case GS_Clearing:
+		self.PlayTime += delta
+		self.updateClearing(delta)
+	}
+	if self.State == GS_Playing || self.State == GS_Clearing {
+		self.mode.Update(self, delta)
+	}
+}
+
+//-------------------------------------------------------------------------
+// GameSession::HandleAction
+//-------------------------------------------------------------------------
+
+func (self *GameSession) handleActionPlaying(action Action) {
+	switch action {
+	case MoveLeft, MoveRight, RotateCW, RotateCCW:
+		self.pieceInputs++
+	}
+	switch action {
+	case MoveLeft:
+		self.shift(-1)
+	case MoveRight:
+		self.shift(1)
+	case RotateCW:
+		self.rotate(true)
+	case RotateCCW:
+		self.rotate(false)
+	case HardDrop:
+		distance := self.Field.DropDistance(self.Figure)
+		self.Figure.Y += distance
+		self.AddScore(distance * hardDropPoints)
+		if distance > 0 {
+			self.lastRotation = false
+		}
+		self.lock()
+	case Hold:
+		self.hold()
+	case Pause:
+		self.pause()
+	}
+}
+
+// while lines are being cleared only pausing is possible
+func (self *GameSession) handleActionClearing(action Action) {
+	if action == Pause {
+		self.pause()
+	}
+}
+
+func (self *GameSession) handleActionPaused(action Action) {
+	if action == Pause {
+		self.State = self.pausedState
+	}
+}
+
+func (self *GameSession) pause() {
+	self.pausedState = self.State
+	self.State = GS_Paused
+}
+
+// Actions are ignored when the game has ended, restarting is up to the
+// frontend (see Reset). MoveLeft, MoveRight and SoftDrop are considered held
+// until ReleaseAction, frontends must not repeat them on their own.
+func (self *GameSession) HandleAction(action Action) {
+	if self.recording() {
+		self.Replay.addInput(action, true)
+	}
+	switch action {
+	case MoveLeft:
+		self.leftHeld = true
+		self.startShift(-1)
+	case MoveRight:
+		self.rightHeld = true
+		self.startShift(1)
+	case SoftDrop:
+		self.softDrop = true
+	}
+
+	switch self.State {
+	case GS_Playing:
+		self.handleActionPlaying(action)
+	case GS_Clearing:
+		self.handleActionClearing(action)
+	case GS_Paused:
+		self.handleActionPaused(action)
+	}
+}
+
+// Tells the session that a held action (e.g. SoftDrop) was released, it is
+// accepted in any state so that nothing gets stuck across pauses.
+func (self *GameSession) ReleaseAction(action Action) {
+	if self.recording() {
+		self.Replay.addInput(action, false)
+	}
+	switch action {
+	case SoftDrop:
+		self.softDrop = false
+	case MoveLeft:
+		self.leftHeld = false
+		if self.shiftDir == -1 {
+			self.stopShift()
+		}
+	case MoveRight:
+		self.rightHeld = false
+		if self.shiftDir == 1 {
+			self.stopShift()
+		}
+	}
+}
+
+func (self *GameSession) startShift(dir int) {
+	self.shiftDir = dir
+	self.dasTime = 0
+	self.arrTime = 0
+}
+
+// when one direction is released while the other one is still held, the
+// figure starts shifting in that direction
+func (self *GameSession) stopShift() {
+	switch {
+	case self.leftHeld:
+		self.startShift(-1)
+	case self.rightHeld:
+		self.startShift(1)
+	default:
+		self.shiftDir = 0
+	}
+}
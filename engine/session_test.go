@@ -0,0 +1,75 @@
+package engine
+
+import "testing"
+
+func TestAutoShift(t *testing.T) {
+	config := DefaultConfig()
+	config.Width = 20 // room to move for a while
+	gs := NewGameSession(config, 1)
+	x := gs.Figure.X
+
+	gs.HandleAction(MoveLeft)
+	for _, c := range []struct {
+		delta uint32 // time passed since the previous check
+		moves int    // moves made since the key was pressed
+	}{
+		{0, 1},
+		{config.DAS - 1, 1},
+		{1, 2},
+		{config.ARR - 1, 2},
+		{1, 3},
+		{config.ARR, 4},
+	} {
+		gs.Update(c.delta)
+		if gs.Figure.X != x-c.moves {
+			t.Fatalf("figure at x %d after %d ms, want %d",
+				gs.Figure.X, gs.time, x-c.moves)
+		}
+	}
+}
+
+func TestPauseWhileClearing(t *testing.T) {
+	config := DefaultConfig()
+	gs := NewGameSession(config, 1)
+	bottom := gs.Field.Blocks[(gs.Field.Height-1)*gs.Field.Width:]
+	for i := range bottom {
+		bottom[i].Filled = true
+	}
+	gs.HandleAction(HardDrop)
+	if gs.State != GS_Clearing {
+		t.Fatalf("state %d after completing a line, want GS_Clearing", gs.State)
+	}
+
+	gs.HandleAction(Pause)
+	gs.Update(config.LineClearDelay)
+	if gs.State != GS_Paused || len(gs.ClearingRows) != 1 {
+		t.Fatalf("state %d with %d rows to clear, want a pause with the row waiting",
+			gs.State, len(gs.ClearingRows))
+	}
+
+	gs.HandleAction(Pause)
+	if gs.State != GS_Clearing {
+		t.Fatalf("state %d after the pause, want GS_Clearing", gs.State)
+	}
+	gs.Update(config.LineClearDelay)
+	if gs.State != GS_Playing || gs.ClearingRows != nil {
+		t.Fatalf("state %d after the line clear delay, want GS_Playing", gs.State)
+	}
+}
+
+func TestSpawnCentred(t *testing.T) {
+	for name, rotation := range RotationSystems {
+		for class := uint32(0); class < uint32(len(specs)); class++ {
+			for width := MinWidth; width <= 11; width++ {
+				f := spawnFigure(rotation, class, width)
+				minCol, cols := figureSpan(f)
+				left := f.X + minCol
+				right := width - left - cols
+				if left < 0 || right < left || right-left > 1 {
+					t.Errorf("%s rotation, class %d, width %d: %d free columns on the left, %d on the right",
+						name, class, width, left, right)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"math/rand"
+)
+
+//-------------------------------------------------------------------------
+// countingSource
+//-------------------------------------------------------------------------
+
+// rand.Source which counts numbers drawn since it was seeded, the standard
+// source keeps its state private, so the only way to restore a state is to
+// reseed it and draw the same amount of numbers again
+type countingSource struct {
+	src   rand.Source
+	seed  int64
+	draws uint64
+}
+
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{src: rand.NewSource(seed), seed: seed}
+}
+
+func (self *countingSource) Int63() int64 {
+	self.draws++
+	return self.src.Int63()
+}
+
+func (self *countingSource) Seed(seed int64) {
+	self.src.Seed(seed)
+	self.seed = seed
+	self.draws = 0
+}
+
+func (self *countingSource) restore(seed int64, draws uint64) {
+	self.Seed(seed)
+	for i := uint64(0); i < draws; i++ {
+		self.Int63()
+	}
+}
+
+//-------------------------------------------------------------------------
+// Snapshot
+//-------------------------------------------------------------------------
+
+// A saved state of a GameSession, see GameSession.Snapshot.
+type Snapshot struct {
+	session GameSession
+	seed    int64
+	draws   uint64
+}
+
+// Saves the complete state of the session, the session can be brought back
+// to it with Restore any number of times.
+func (self *GameSession) Snapshot() *Snapshot {
+	s := &Snapshot{session: *self, seed: self.source.seed, draws: self.source.draws}
+	s.session.detach()
+	s.session.OnClear = nil
+	s.session.OnEnd = nil
+	s.session.Replay = nil
+	return s
+}
+
+// Brings the session back to the snapshot's state. Callbacks and the replay
+// being recorded stay as they are.
+func (self *GameSession) Restore(s *Snapshot) {
+	onClear, onEnd, replay := self.OnClear, self.OnEnd, self.Replay
+	source := self.source
+	*self = s.session
+	self.detach()
+	self.OnClear, self.OnEnd, self.Replay = onClear, onEnd, replay
+	self.source = source
+	self.source.restore(s.seed, s.draws)
+}
+
+// makes deep copies of everything the session shares with the session it
+// was copied from (the rng aside, Snapshot and Restore take care of it)
+func (self *GameSession) detach() {
+	field := *self.Field
+	field.Blocks = append([]TetrisBlock(nil), field.Blocks...)
+	self.Field = &field
+
+	self.Figure = copyFigure(self.Figure)
+	self.HoldFigure = copyFigure(self.HoldFigure)
+	queue := make([]*TetrisFigure, len(self.Queue))
+	for i, figure := range self.Queue {
+		queue[i] = copyFigure(figure)
+	}
+	self.Queue = queue
+	self.ClearingRows = append([]int(nil), self.ClearingRows...)
+
+	scoring := *self.scoring
+	self.scoring = &scoring
+	self.generator = self.generator.Clone(self.rand)
+}
+
+func copyFigure(figure *TetrisFigure) *TetrisFigure {
+	if figure == nil {
+		return nil
+	}
+	c := *figure
+	return &c
+}
@@ -0,0 +1,57 @@
+package engine
+
+//-------------------------------------------------------------------------
+// T-spin detection
+//-------------------------------------------------------------------------
+
+// T-spin types
+const (
+	TSpinNone = iota
+	TSpinMini
+	TSpinFull
+)
+
+// kick index which turns a mini T-spin into a full one (the SRS 1x2 kick)
+const tspinFullKick = 4
+
+// Checks whether locking the figure at its current position is a T-spin,
+// using the 3-corner rule. Only makes sense if the last thing that moved the
+// figure was a rotation, kick is the kick index returned by the rotation.
+func DetectTSpin(field *TetrisField, figure *TetrisFigure, kick int) int {
+	if figure.Class != classT || figure.CenterX == -1 {
+		return TSpinNone
+	}
+
+	cx, cy := figure.X+figure.CenterX, figure.Y+figure.CenterY
+	corners := 0
+	for _, d := range [...][2]int{{-1, -1}, {1, -1}, {-1, 1}, {1, 1}} {
+		if field.occupied(cx+d[0], cy+d[1]) {
+			corners++
+		}
+	}
+	if corners < 3 {
+		return TSpinNone
+	}
+
+	// the T points away from the only empty side of its center, the two
+	// corners on that side are the front ones
+	var dx, dy int
+	for _, d := range [...][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}} {
+		x, y := figure.CenterX+d[0], figure.CenterY+d[1]
+		if x < 0 || y < 0 || x >= 4 || y >= 4 || !figure.Blocks[y*4+x].Filled {
+			dx, dy = -d[0], -d[1]
+			break
+		}
+	}
+	front := 0
+	if field.occupied(cx+dx+dy, cy+dy+dx) {
+		front++
+	}
+	if field.occupied(cx+dx-dy, cy+dy-dx) {
+		front++
+	}
+	if front == 2 || kick == tspinFullKick {
+		return TSpinFull
+	}
+	return TSpinMini
+}
@@ -0,0 +1,89 @@
+package engine
+
+import "testing"
+
+func TestDetectTSpin(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		rows     []string
+		rotation int
+		cx, cy   int // where the center of the T goes
+		kick     int
+		tspin    int
+		lines    int
+	}{
+		{"TSD", []string{
+			"..........",
+			"..........",
+			"..........",
+			".#........",
+			"#...######",
+			"##.#######",
+		}, RotationTwo, 2, 4, 0, TSpinFull, 2},
+		{"TST", []string{
+			"..........",
+			"..........",
+			"..........",
+			"###.######",
+			"##..######",
+			"###.######",
+		}, RotationLeft, 3, 4, 0, TSpinFull, 3},
+		// only one of the corners the T points to is filled
+		{"mini", []string{
+			"..........",
+			"..........",
+			"..........",
+			"..........",
+			"#.........",
+			"...#######",
+		}, RotationSpawn, 1, 5, 0, TSpinMini, 1},
+		// the 1x2 kick makes any 3-corner T-spin a full one
+		{"mini with the last kick", []string{
+			"..........",
+			"..........",
+			"..........",
+			"..........",
+			"#.........",
+			"...#######",
+		}, RotationSpawn, 1, 5, tspinFullKick, TSpinFull, 1},
+		{"two corners", []string{
+			"..........",
+			"..........",
+			"..........",
+			"..........",
+			"..........",
+			"...#######",
+		}, RotationSpawn, 1, 5, 0, TSpinNone, 1},
+	} {
+		field := testField(c.rows...)
+		f := srsFigure(classT, c.rotation)
+		f.X, f.Y = c.cx-f.CenterX, c.cy-f.CenterY
+		if field.Collide(f) {
+			t.Fatalf("%s: the T doesn't fit", c.name)
+		}
+		if tspin := DetectTSpin(field, f, c.kick); tspin != c.tspin {
+			t.Errorf("%s: T-spin type %d, want %d", c.name, tspin, c.tspin)
+		}
+		field.Merge(f)
+		if lines := len(field.FullLines()); lines != c.lines {
+			t.Errorf("%s: %d lines, want %d", c.name, lines, c.lines)
+		}
+	}
+}
+
+func TestDetectTSpinOtherFigures(t *testing.T) {
+	field := testField(
+		"..........",
+		"#.........",
+		"...#######",
+	)
+	// an S with three corners around its center filled
+	f := srsFigure(0, RotationSpawn)
+	f.X, f.Y = 1-f.CenterX, 2-f.CenterY
+	if field.Collide(f) {
+		t.Fatal("the figure doesn't fit")
+	}
+	if tspin := DetectTSpin(field, f, 0); tspin != TSpinNone {
+		t.Fatalf("T-spin type %d for a figure other than T", tspin)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"github.com/0xe2-0x9a-0x9b/Go-SDL/sdl"
+	"github.com/nsf/gotris/engine"
+	"reflect"
+	"testing"
+)
+
+func key(sym uint32, down bool) sdl.KeyboardEvent {
+	e := sdl.KeyboardEvent{Type: sdl.KEYUP}
+	if down {
+		e.Type = sdl.KEYDOWN
+	}
+	e.Keysym.Sym = sym
+	return e
+}
+
+func TestEventsReplayInOrder(t *testing.T) {
+	g := newTestGame()
+	g.keys = DefaultKeyBindings()
+	g.StartRecording()
+
+	// DAS kicks in between the updates, the replay has to see the release
+	// at the same point of the game
+	for _, step := range []struct {
+		delta uint32
+		event interface{}
+	}{
+		{16, key(sdl.K_LEFT, true)},
+		{100, nil},
+		{100, key(sdl.K_LEFT, false)},
+		{16, key(sdl.K_UP, true)},
+		{16, key(sdl.K_UP, false)},
+		{16, key(sdl.K_SPACE, true)},
+		{16, key(sdl.K_SPACE, false)},
+		{16, key(sdl.K_RIGHT, true)},
+		{300, key(sdl.K_RIGHT, false)},
+	} {
+		if !g.Step(step.delta, step.event) {
+			t.Fatal("the game quit")
+		}
+	}
+
+	p := engine.NewReplayPlayer(g.Replay)
+	for p.Step() {
+	}
+	if *p.Figure != *g.Figure || !reflect.DeepEqual(p.Field.Blocks, g.Field.Blocks) {
+		t.Fatal("the replay diverged from the game")
+	}
+}
+
+func TestQuitEvent(t *testing.T) {
+	g := newTestGame()
+	if g.HandleEvent(sdl.QuitEvent{Type: sdl.QUIT}) {
+		t.Fatal("the game didn't quit")
+	}
+}
+
+func TestEventsDASTiming(t *testing.T) {
+	g := newTestGame()
+	g.keys = DefaultKeyBindings()
+	config := g.Config()
+	x := g.Figure.X
+
+	// the time of a frame before the press doesn't charge DAS and the
+	// time up to the release still counts
+	for i, step := range []struct {
+		delta uint32
+		event interface{}
+		moves int
+	}{
+		{9, key(sdl.K_LEFT, true), 1},
+		{config.DAS - 1, nil, 1},
+		{1, nil, 2},
+		{config.ARR - 1, nil, 2},
+		{1, key(sdl.K_LEFT, false), 3},
+		{config.ARR, nil, 3},
+	} {
+		g.Step(step.delta, step.event)
+		if g.Figure.X != x-step.moves {
+			t.Fatalf("step %d: %d moves, want %d", i, x-g.Figure.X, step.moves)
+		}
+	}
+}
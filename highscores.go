@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nsf/gotris/engine"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// how many best results are kept in every table
+const maxHighScores = 10
+
+// names longer than that are cut
+const maxNameLength = 12
+
+//-------------------------------------------------------------------------
+// HighScores
+//-------------------------------------------------------------------------
+
+// modes where a faster time beats a higher score
+var timedModes = map[string]bool{
+	"sprint": true,
+	"dig":    true,
+}
+
+// modes which don't keep high scores
+var unrankedModes = map[string]bool{
+	"zen": true,
+}
+
+// modes with a goal, only games which reach it go into the table
+var goalModes = map[string]bool{
+	"sprint": true,
+	"ultra":  true,
+	"dig":    true,
+}
+
+type HighScore struct {
+	Name   string
+	Score  int
+	Lines  int
+	Level  int
+	Time   uint32 // milliseconds
+	Pieces int
+	Date   time.Time
+}
+
+func (self *HighScore) beats(other *HighScore, timed bool) bool {
+	if timed {
+		return self.Time < other.Time
+	}
+	return self.Score > other.Score
+}
+
+// Name of the table for games played with the config: the mode and the
+// rules which change what a result is worth, e.g. "sprint 40 lines, 10x25".
+// Handling settings (rotation, DAS and so on) are up to the player.
+func tableName(config *engine.Config) string {
+	name := config.Mode
+	switch config.Mode {
+	case "sprint":
+		name += fmt.Sprintf(" %d lines", config.LineGoal)
+	case "ultra":
+		name += fmt.Sprintf(" %d:%02d", config.TimeLimit/60000, config.TimeLimit/1000%60)
+	case "dig":
+		name += fmt.Sprintf(" %d rows", config.GarbageRows)
+	}
+	if !timedModes[config.Mode] {
+		// points are multiplied by the level
+		name += fmt.Sprintf(", level %d", config.Level)
+	}
+	return fmt.Sprintf("%s, %dx%d", name, config.Width, config.Height)
+}
+
+// High score tables by name (see tableName), stored as JSON. Tables are
+// sorted best first, by time if they are timed (see timedModes) and by score
+// otherwise, among equal results the older one wins.
+type HighScores struct {
+	filename string
+	Tables   map[string][]HighScore
+}
+
+// It's not an error if the file doesn't exist, the table is empty then.
+func LoadHighScores(filename string) (*HighScores, error) {
+	scores := &HighScores{filename: filename, Tables: make(map[string][]HighScore)}
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return scores, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(scores); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if scores.Tables == nil {
+		scores.Tables = make(map[string][]HighScore)
+	}
+	return scores, nil
+}
+
+// writes the table to a temporary file first, so that a failed write
+// doesn't lose the old scores
+func (self *HighScores) Save() error {
+	if err := os.MkdirAll(filepath.Dir(self.filename), 0755); err != nil {
+		return err
+	}
+	tmp := self.filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(self); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, self.filename)
+}
+
+func (self *HighScores) Table(name string) []HighScore {
+	return self.Tables[name]
+}
+
+// whether the entry would make it into the table
+func (self *HighScores) Qualifies(name string, timed bool, entry *HighScore) bool {
+	if !timed && entry.Score == 0 {
+		return false
+	}
+	table := self.Tables[name]
+	return len(table) < maxHighScores || entry.beats(&table[len(table)-1], timed)
+}
+
+// Adds the entry to the table, returns its place (0 is the best one) or -1
+// if it didn't make it.
+func (self *HighScores) Add(name string, timed bool, entry HighScore) int {
+	if !self.Qualifies(name, timed, &entry) {
+		return -1
+	}
+	table := self.Tables[name]
+	i := sort.Search(len(table), func(i int) bool {
+		return entry.beats(&table[i], timed)
+	})
+	table = append(table, HighScore{})
+	copy(table[i+1:], table[i:])
+	table[i] = entry
+	if len(table) > maxHighScores {
+		table = table[:maxHighScores]
+	}
+	self.Tables[name] = table
+	return i
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"github.com/0xe2-0x9a-0x9b/Go-SDL/sdl"
+	"github.com/nsf/gotris/engine"
+)
+
+// how often to look for new joysticks while there are none (ms)
+const joystickRescanInterval = 3000
+
+//-------------------------------------------------------------------------
+// joystick input names
+//-------------------------------------------------------------------------
+
+// Joystick inputs are encoded as uint32 to share the bindings code with
+// keys: buttons are their index, hat directions are joyHatBase + hat*16 +
+// direction bit.
+const (
+	joyHatBase    = 0x100
+	maxJoyButtons = 32
+	maxJoyHats    = 4
+)
+
+var hatDirections = [...]uint8{sdl.HAT_UP, sdl.HAT_RIGHT, sdl.HAT_DOWN, sdl.HAT_LEFT}
+
+func joyButton(button uint8) uint32 {
+	return uint32(button)
+}
+
+func joyHat(hat, dir uint8) uint32 {
+	return joyHatBase + uint32(hat)*16 + uint32(dir)
+}
+
+// button0..button31, hat0-up, hat0-right, ..., hat3-left
+var joyNames = map[string]uint32{}
+
+func init() {
+	for i := 0; i < maxJoyButtons; i++ {
+		joyNames[fmt.Sprintf("button%d", i)] = joyButton(uint8(i))
+	}
+	dirNames := map[uint8]string{
+		sdl.HAT_UP:    "up",
+		sdl.HAT_RIGHT: "right",
+		sdl.HAT_DOWN:  "down",
+		sdl.HAT_LEFT:  "left",
+	}
+	for h := 0; h < maxJoyHats; h++ {
+		for dir, name := range dirNames {
+			joyNames[fmt.Sprintf("hat%d-%s", h, name)] = joyHat(uint8(h), dir)
+		}
+	}
+}
+
+//-------------------------------------------------------------------------
+// JoyBindings
+//-------------------------------------------------------------------------
+
+type JoyBindings map[uint32]engine.Action
+
+func DefaultJoyBindings() JoyBindings {
+	return JoyBindings{
+		joyHat(0, sdl.HAT_LEFT):  engine.MoveLeft,
+		joyHat(0, sdl.HAT_RIGHT): engine.MoveRight,
+		joyHat(0, sdl.HAT_DOWN):  engine.SoftDrop,
+		joyHat(0, sdl.HAT_UP):    engine.HardDrop,
+		joyButton(0):             engine.RotateCCW,
+		joyButton(1):             engine.RotateCW,
+		joyButton(2):             engine.Hold,
+		joyButton(3):             engine.HardDrop,
+		joyButton(6):             actionRestart,
+		joyButton(7):             engine.Pause,
+	}
+}
+
+// Same format as the key bindings file (see parseBindings), with inputs
+// named like button0 or hat0-left.
+func LoadJoyBindings(filename string) (JoyBindings, error) {
+	bindings, err := loadBindings(filename, joyNames, DefaultJoyBindings())
+	return JoyBindings(bindings), err
+}
+
+//-------------------------------------------------------------------------
+// JoystickInput
+//-------------------------------------------------------------------------
+
+type joyAction struct {
+	Action  engine.Action
+	Pressed bool
+}
+
+// Turns joystick events into presses and releases of bound actions. A hat
+// reports its whole state at once, so presses and releases of its
+// directions are synthesized by comparing it with the previous state.
+type JoystickInput struct {
+	bindings JoyBindings
+	hats     map[[2]uint8]uint8 // (joystick, hat) -> last value
+}
+
+func NewJoystickInput(bindings JoyBindings) *JoystickInput {
+	return &JoystickInput{bindings, make(map[[2]uint8]uint8)}
+}
+
+// Forgets the state of all hats, for when the devices were reopened and
+// their events start anew. Returns releases of the directions which were
+// held, so that nothing stays held after the reset.
+func (self *JoystickInput) Reset() []joyAction {
+	var actions []joyAction
+	for hat, value := range self.hats {
+		for _, dir := range hatDirections {
+			if value&dir == 0 {
+				continue
+			}
+			if action, ok := self.bindings[joyHat(hat[1], dir)]; ok {
+				actions = append(actions, joyAction{action, false})
+			}
+		}
+	}
+	self.hats = make(map[[2]uint8]uint8)
+	return actions
+}
+
+func (self *JoystickInput) Translate(event interface{}) []joyAction {
+	switch e := event.(type) {
+	case sdl.JoyButtonEvent:
+		action, ok := self.bindings[joyButton(e.Button)]
+		if !ok {
+			return nil
+		}
+		return []joyAction{{action, e.Type == sdl.JOYBUTTONDOWN}}
+	case sdl.JoyHatEvent:
+		hat := [2]uint8{e.Which, e.Hat}
+		old := self.hats[hat]
+		self.hats[hat] = e.Value
+
+		// releases go first, so that rolling from left to right doesn't
+		// leave the figure thinking both are held
+		var actions []joyAction
+		for _, pressed := range [...]bool{false, true} {
+			for _, dir := range hatDirections {
+				was, is := old&dir != 0, e.Value&dir != 0
+				if was == is || is != pressed {
+					continue
+				}
+				if action, ok := self.bindings[joyHat(e.Hat, dir)]; ok {
+					actions = append(actions, joyAction{action, pressed})
+				}
+			}
+		}
+		return actions
+	}
+	return nil
+}
+
+//-------------------------------------------------------------------------
+// Joysticks
+//-------------------------------------------------------------------------
+
+// Keeps all attached joysticks open. SDL 1.2 has no hot-plug events, it
+// only sees devices which were there when the joystick subsystem started and
+// keeps handles of unplugged ones open without a word. So every
+// joystickRescanInterval the subsystem is restarted to pick up devices
+// plugged in (or back in) since, but only while the game is idle (see
+// Update): a restart takes a while and forgets which buttons are held.
+type Joysticks struct {
+	opened     []*sdl.Joystick
+	rescanTime uint32
+}
+
+func (self *Joysticks) Open() {
+	for i := 0; i < sdl.NumJoysticks(); i++ {
+		if j := sdl.JoystickOpen(i); j != nil {
+			self.opened = append(self.opened, j)
+		}
+	}
+	sdl.JoystickEventState(sdl.ENABLE)
+}
+
+func (self *Joysticks) Close() {
+	for _, j := range self.opened {
+		j.Close()
+	}
+	self.opened = nil
+}
+
+// idle tells whether nothing is being played at the moment (the game is
+// paused or over), joysticks are rescanned only then. Returns true if the
+// devices were reopened.
+func (self *Joysticks) Update(delta uint32, idle bool) bool {
+	if !idle {
+		self.rescanTime = 0
+		return false
+	}
+	self.rescanTime += delta
+	if self.rescanTime < joystickRescanInterval {
+		return false
+	}
+	self.rescanTime = 0
+
+	self.Close()
+	sdl.QuitSubSystem(sdl.INIT_JOYSTICK)
+	sdl.InitSubSystem(sdl.INIT_JOYSTICK)
+	self.Open()
+	return true
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/0xe2-0x9a-0x9b/Go-SDL/sdl"
+	"github.com/nsf/gotris/engine"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestGame() *Game {
+	g := new(Game)
+	g.GameSession = engine.NewGameSession(engine.DefaultConfig(), 1)
+	g.joy = NewJoystickInput(DefaultJoyBindings())
+	return g
+}
+
+func hat(value uint8) sdl.JoyHatEvent {
+	return sdl.JoyHatEvent{Type: sdl.JOYHATMOTION, Value: value}
+}
+
+func button(b uint8, down bool) sdl.JoyButtonEvent {
+	e := sdl.JoyButtonEvent{Type: sdl.JOYBUTTONUP, Button: b, State: sdl.RELEASED}
+	if down {
+		e.Type, e.State = sdl.JOYBUTTONDOWN, sdl.PRESSED
+	}
+	return e
+}
+
+func TestJoystickHatTransitions(t *testing.T) {
+	in := NewJoystickInput(DefaultJoyBindings())
+	steps := []struct {
+		value uint8
+		want  []joyAction
+	}{
+		{sdl.HAT_LEFT, []joyAction{{engine.MoveLeft, true}}},
+		{sdl.HAT_LEFT | sdl.HAT_DOWN, []joyAction{{engine.SoftDrop, true}}},
+		{sdl.HAT_RIGHT, []joyAction{
+			{engine.SoftDrop, false},
+			{engine.MoveLeft, false},
+			{engine.MoveRight, true},
+		}},
+		{sdl.HAT_CENTERED, []joyAction{{engine.MoveRight, false}}},
+		{sdl.HAT_CENTERED, nil},
+	}
+	for i, s := range steps {
+		got := in.Translate(hat(s.value))
+		if !reflect.DeepEqual(got, s.want) {
+			t.Errorf("step %d: got %v, want %v", i, got, s.want)
+		}
+	}
+}
+
+func TestJoystickReset(t *testing.T) {
+	in := NewJoystickInput(DefaultJoyBindings())
+	in.Translate(hat(sdl.HAT_LEFT))
+	if got, want := in.Reset(), []joyAction{{engine.MoveLeft, false}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reset: got %v, want %v", got, want)
+	}
+	// the hat was released while the devices were reopened
+	if got, want := in.Translate(hat(sdl.HAT_LEFT)), []joyAction{{engine.MoveLeft, true}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("press after the reset: got %v, want %v", got, want)
+	}
+}
+
+func TestJoystickDrivesSession(t *testing.T) {
+	g := newTestGame()
+
+	x := g.Figure.X
+	g.HandleJoyEvent(hat(sdl.HAT_LEFT))
+	g.HandleJoyEvent(hat(sdl.HAT_CENTERED))
+	if g.Figure.X != x-1 {
+		t.Fatalf("hat left: figure at X = %d, want %d", g.Figure.X, x-1)
+	}
+
+	// unbound buttons are ignored
+	g.HandleJoyEvent(button(20, true))
+	g.HandleJoyEvent(button(20, false))
+
+	g.HandleJoyEvent(button(7, true))
+	g.HandleJoyEvent(button(7, false))
+	if g.State != engine.GS_Paused {
+		t.Fatalf("start button: state = %d, want GS_Paused", g.State)
+	}
+}
+
+func TestJoystickBindingsFile(t *testing.T) {
+	const file = `
+# swap rotation buttons, pause on select
+rotate-cw = button0
+rotate-ccw = button1
+pause = button6, hat1-up
+`
+	b, err := parseBindings(strings.NewReader(file), "joystick", joyNames, DefaultJoyBindings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint32]engine.Action{
+		joyButton(0):          engine.RotateCW,
+		joyButton(1):          engine.RotateCCW,
+		joyButton(6):          engine.Pause,
+		joyHat(1, sdl.HAT_UP): engine.Pause,
+	}
+	for input, action := range want {
+		if b[input] != action {
+			t.Errorf("input %#x: got %v, want %v", input, b[input], action)
+		}
+	}
+	if _, ok := b[joyButton(7)]; ok {
+		t.Errorf("default pause button is still bound")
+	}
+
+	for _, bad := range []string{"hold = button99", "hold = hat0-middle", "hold = button1\npause = button1"} {
+		if _, err := parseBindings(strings.NewReader(bad), "joystick", joyNames, nil); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/0xe2-0x9a-0x9b/Go-SDL/sdl"
+	"github.com/nsf/gotris/engine"
+	"io"
+	"os"
+	"strings"
+)
+
+// Frontend only actions, they share key bindings with the engine actions,
+// but never reach the GameSession.
+const (
+	actionQuit engine.Action = -1 - iota
+	actionRestart
+	actionScores // shows or hides the high score table
+)
+
+var frontendActionNames = map[engine.Action]string{
+	actionQuit:    "quit",
+	actionRestart: "restart",
+	actionScores:  "high-scores",
+}
+
+func actionName(action engine.Action) string {
+	if name, ok := frontendActionNames[action]; ok {
+		return name
+	}
+	return action.String()
+}
+
+func parseActionName(name string) (engine.Action, bool) {
+	for action, n := range frontendActionNames {
+		if n == name {
+			return action, true
+		}
+	}
+	return engine.ParseAction(name)
+}
+
+//-------------------------------------------------------------------------
+// key names
+//-------------------------------------------------------------------------
+
+var keyNames = map[string]uint32{
+	"left":         sdl.K_LEFT,
+	"right":        sdl.K_RIGHT,
+	"up":           sdl.K_UP,
+	"down":         sdl.K_DOWN,
+	"space":        sdl.K_SPACE,
+	"return":       sdl.K_RETURN,
+	"escape":       sdl.K_ESCAPE,
+	"tab":          sdl.K_TAB,
+	"backspace":    sdl.K_BACKSPACE,
+	"insert":       sdl.K_INSERT,
+	"delete":       sdl.K_DELETE,
+	"home":         sdl.K_HOME,
+	"end":          sdl.K_END,
+	"pageup":       sdl.K_PAGEUP,
+	"pagedown":     sdl.K_PAGEDOWN,
+	"lshift":       sdl.K_LSHIFT,
+	"rshift":       sdl.K_RSHIFT,
+	"lctrl":        sdl.K_LCTRL,
+	"rctrl":        sdl.K_RCTRL,
+	"lalt":         sdl.K_LALT,
+	"ralt":         sdl.K_RALT,
+	"comma":        sdl.K_COMMA,
+	"period":       sdl.K_PERIOD,
+	"slash":        sdl.K_SLASH,
+	"semicolon":    sdl.K_SEMICOLON,
+	"quote":        sdl.K_QUOTE,
+	"backquote":    sdl.K_BACKQUOTE,
+	"minus":        sdl.K_MINUS,
+	"equals":       sdl.K_EQUALS,
+	"leftbracket":  sdl.K_LEFTBRACKET,
+	"rightbracket": sdl.K_RIGHTBRACKET,
+	"backslash":    sdl.K_BACKSLASH,
+	"kp_enter":     sdl.K_KP_ENTER,
+	"kp_plus":      sdl.K_KP_PLUS,
+	"kp_minus":     sdl.K_KP_MINUS,
+	"kp_multiply":  sdl.K_KP_MULTIPLY,
+	"kp_divide":    sdl.K_KP_DIVIDE,
+	"kp_period":    sdl.K_KP_PERIOD,
+}
+
+func init() {
+	// SDL key codes of letters and digits are their ASCII codes, function
+	// keys and keypad digits are consecutive
+	for c := 'a'; c <= 'z'; c++ {
+		keyNames[string(c)] = uint32(c)
+	}
+	for c := '0'; c <= '9'; c++ {
+		keyNames[string(c)] = uint32(c)
+		keyNames["kp"+string(c)] = sdl.K_KP0 + uint32(c-'0')
+	}
+	for i := 0; i < 12; i++ {
+		keyNames[fmt.Sprintf("f%d", i+1)] = sdl.K_F1 + uint32(i)
+	}
+}
+
+func keyName(key uint32) string {
+	for name, k := range keyNames {
+		if k == key {
+			return name
+		}
+	}
+	return fmt.Sprintf("key %d", key)
+}
+
+//-------------------------------------------------------------------------
+// KeyBindings
+//-------------------------------------------------------------------------
+
+type KeyBindings map[uint32]engine.Action
+
+func DefaultKeyBindings() KeyBindings {
+	return KeyBindings{
+		sdl.K_LEFT:   engine.MoveLeft,
+		sdl.K_a:      engine.MoveLeft,
+		sdl.K_j:      engine.MoveLeft,
+		sdl.K_RIGHT:  engine.MoveRight,
+		sdl.K_d:      engine.MoveRight,
+		sdl.K_l:      engine.MoveRight,
+		sdl.K_UP:     engine.RotateCW,
+		sdl.K_w:      engine.RotateCW,
+		sdl.K_i:      engine.RotateCW,
+		sdl.K_z:      engine.RotateCCW,
+		sdl.K_DOWN:   engine.SoftDrop,
+		sdl.K_s:      engine.SoftDrop,
+		sdl.K_k:      engine.SoftDrop,
+		sdl.K_SPACE:  engine.HardDrop,
+		sdl.K_c:      engine.Hold,
+		sdl.K_LSHIFT: engine.Hold,
+		sdl.K_p:      engine.Pause,
+		sdl.K_r:      actionRestart,
+		sdl.K_ESCAPE: actionQuit,
+		sdl.K_TAB:    actionScores,
+	}
+}
+
+// the first key (in key code order) bound to the action, for hints like
+// "press P to resume"
+func (self KeyBindings) KeyFor(action engine.Action) (uint32, bool) {
+	found := false
+	var key uint32
+	for k, a := range self {
+		if a == action && (!found || k < key) {
+			key, found = k, true
+		}
+	}
+	return key, found
+}
+
+// Loads default key bindings and applies the ones from the file on top of
+// them. It's not an error if the file doesn't exist.
+func LoadKeyBindings(filename string) (KeyBindings, error) {
+	bindings, err := loadBindings(filename, keyNames, DefaultKeyBindings())
+	return KeyBindings(bindings), err
+}
+
+func loadBindings(filename string, names map[string]uint32,
+	defaults map[uint32]engine.Action) (map[uint32]engine.Action, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return defaults, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseBindings(f, filename, names, defaults)
+}
+
+// The file consists of lines like:
+//
+//	# comment
+//	move-left = left, a
+//	hard-drop = space
+//
+// where the right side lists input names (keys, joystick buttons) from the
+// names map. Every action mentioned in the file loses its default inputs (an
+// empty list leaves the action unbound), so do default inputs the file uses
+// for something else. Within the file an input may be bound to one action
+// only.
+func parseBindings(r io.Reader, filename string, names map[string]uint32,
+	defaults map[uint32]engine.Action) (map[uint32]engine.Action, error) {
+	fileBindings := make(map[uint32]engine.Action)
+	seenActions := make(map[engine.Action]int)
+
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", filename, lineNum,
+				fmt.Sprintf(format, args...))
+		}
+
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			return nil, errorf("expected 'action = input, ...', got: %q", line)
+		}
+		name := strings.TrimSpace(line[:eq])
+		action, ok := parseActionName(name)
+		if !ok {
+			return nil, errorf("unknown action: %q", name)
+		}
+		if prev, ok := seenActions[action]; ok {
+			return nil, errorf("action %q is already bound on line %d", name, prev)
+		}
+		seenActions[action] = lineNum
+
+		inputs := strings.TrimSpace(line[eq+1:])
+		if inputs == "" {
+			continue
+		}
+		for _, in := range strings.Split(inputs, ",") {
+			in = strings.ToLower(strings.TrimSpace(in))
+			input, ok := names[in]
+			if !ok {
+				return nil, errorf("unknown input name: %q", in)
+			}
+			if other, ok := fileBindings[input]; ok {
+				return nil, errorf("%q is bound to both %q and %q",
+					in, actionName(other), name)
+			}
+			fileBindings[input] = action
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	bindings := make(map[uint32]engine.Action)
+	for input, action := range defaults {
+		if _, ok := seenActions[action]; ok {
+			continue
+		}
+		if _, ok := fileBindings[input]; ok {
+			continue
+		}
+		bindings[input] = action
+	}
+	for input, action := range fileBindings {
+		bindings[input] = action
+	}
+	return bindings, nil
+}
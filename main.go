@@ -0,0 +1,863 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/0xe2-0x9a-0x9b/Go-SDL/sdl"
+	"github.com/banthar/gl"
+	"github.com/nsf/gotris/engine"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const blockSize = 15
+const smallBlockSize = 9
+const smallBlockOffset = (blockSize - smallBlockSize) / 2
+
+// vertical distance between figures in the next queue, enough for the
+// tallest (4 blocks) spawn orientation
+const previewSpacing = 4 * blockSize
+
+// how far below the top of the field the next and hold figures start
+const panelOffset = 50
+
+// for how long line clear notifications stay on the screen (ms)
+const clearNoticeTime = 1500
+
+// The window is big enough for the field, the hold and next panels at its
+// sides and the status lines above and below it, but never smaller than
+// minScreenWidth x minScreenHeight.
+const (
+	sidePanelWidth  = 170
+	statusHeight    = 45
+	minScreenWidth  = 640
+	minScreenHeight = 480
+)
+
+// Two lines of text fit above and below the field. Above it the status line
+// goes first and prompts (pause, game over, ...) go under it, below it go
+// the results of the game and the replay status.
+const (
+	statusY       = 5
+	promptY       = 25
+	resultsBottom = 40 // from the bottom of the window
+	replayBottom  = 20
+)
+
+var mode *string = flag.String("mode", "marathon", "game mode: marathon (endless), sprint (clear a number of lines as fast as possible), ultra (score as much as possible in a time limit), dig (clear garbage rows), survival (garbage keeps rising) or zen (no game over)")
+var lineGoal *int = flag.Int("lines", 40, "lines to clear in sprint mode")
+var timeLimit *uint = flag.Uint("time-limit", 120, "length of an ultra game in seconds")
+var garbageRows *int = flag.Int("garbage", 10, "rows of garbage to clear in dig mode")
+var fieldWidth *int = flag.Int("width", 10, "field width in blocks (4..40)")
+var fieldHeight *int = flag.Int("height", 25, "field height in blocks (8..40)")
+var initLevel *int = flag.Int("level", 1, "set initial level to this value (1..20)")
+var randomizer *string = flag.String("randomizer", "classic", "piece generator: classic (no immediate repeats) or bag (7-bag)")
+var rotation *string = flag.String("rotation", "classic", "rotation system: classic or srs (Super Rotation System with wall kicks)")
+var lockDelay *uint = flag.Uint("lock-delay", 500, "milliseconds a figure may rest on the ground before it locks")
+var lockResets *int = flag.Int("lock-resets", 15, "how many times moving or rotating a grounded figure restarts the lock delay")
+var lineClearDelay *uint = flag.Uint("line-clear-delay", 300, "milliseconds complete lines are shown before they collapse")
+var das *uint = flag.Uint("das", 170, "delayed auto shift: milliseconds to hold left/right before the figure starts moving on its own")
+var arr *uint = flag.Uint("arr", 50, "auto repeat rate: milliseconds between moves once DAS kicked in (0 moves straight to the wall)")
+var softDropFactor *int = flag.Int("soft-drop-factor", 20, "how many times faster figures fall while soft drop is held")
+var previews *int = flag.Int("previews", 1, "number of upcoming figures to show (1..6)")
+var ghost *bool = flag.Bool("ghost", true, "show where the current figure will land")
+var keysFile *string = flag.String("keys", configPath("keys"), "key bindings file")
+var joyFile *string = flag.String("joystick", configPath("joystick"), "joystick bindings file")
+var replaysDir *string = flag.String("replays", dataPath("replays"), "directory finished games are recorded into (empty disables recording)")
+var scoresFile *string = flag.String("scores", dataPath("highscores.json"), "high score table file")
+var replayFile *string = flag.String("replay", "", "play back a recorded game instead of playing")
+var seed *int64 = flag.Int64("seed", 0, "random seed for the piece sequence (0 means pick one)")
+
+// returns the -seed value if it was given and a fresh time based seed
+// otherwise
+func sessionSeed() int64 {
+	if *seed != 0 {
+		return *seed
+	}
+	return time.Now().UnixNano()
+}
+
+func drawBlock(x, y int, color engine.TetrisBlockColor) {
+	glx := gl.GLint(x)
+	gly := gl.GLint(y)
+
+	gl.Color3ub(color.R/2, color.G/2, color.B/2)
+	gl.Begin(gl.QUADS)
+	gl.Vertex2i(int(glx), int(gly))
+	gl.Vertex2i(int(glx+blockSize), int(gly))
+	gl.Vertex2i(int(glx+blockSize), int(gly+blockSize))
+	gl.Vertex2i(int(glx), int(gly+blockSize))
+	gl.Color3ub(color.R, color.G, color.B)
+	gl.Vertex2i(int(glx+smallBlockOffset), int(gly+smallBlockOffset))
+	gl.Vertex2i(int(glx+blockSize-smallBlockOffset), int(gly+smallBlockOffset))
+	gl.Vertex2i(int(glx+blockSize-smallBlockOffset), int(gly+blockSize-smallBlockOffset))
+	gl.Vertex2i(int(glx+smallBlockOffset), int(gly+blockSize-smallBlockOffset))
+	gl.End()
+}
+
+func drawTetrisBlock(block *engine.TetrisBlock, x, y int) {
+	if block.Filled {
+		drawBlock(x, y, block.Color)
+	}
+}
+
+func drawTetrisFigure(figure *engine.TetrisFigure, ox, oy int) {
+	ox += (figure.X + 1) * blockSize // skip tetris field wall also
+	oy += figure.Y * blockSize
+	drawFigureAt(figure, ox, oy)
+}
+
+// draws the figure's 4x4 grid with the top left corner at x, y ignoring
+// its position on the field, for the next and hold panels
+func drawFigureAt(figure *engine.TetrisFigure, ox, oy int) {
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			offset := y*4 + x
+			drawTetrisBlock(&figure.Blocks[offset], ox+x*blockSize, oy+y*blockSize)
+		}
+	}
+}
+
+// translucent version of the figure, used for the ghost piece
+func drawGhostFigure(figure *engine.TetrisFigure, ox, oy int) {
+	ox += (figure.X + 1) * blockSize
+	oy += figure.Y * blockSize
+	gl.Begin(gl.QUADS)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			b := &figure.Blocks[y*4+x]
+			if !b.Filled {
+				continue
+			}
+			bx, by := ox+x*blockSize, oy+y*blockSize
+			gl.Color4ub(b.Color.R, b.Color.G, b.Color.B, 60)
+			gl.Vertex2i(bx, by)
+			gl.Vertex2i(bx+blockSize, by)
+			gl.Vertex2i(bx+blockSize, by+blockSize)
+			gl.Vertex2i(bx, by+blockSize)
+		}
+	}
+	gl.End()
+}
+
+func drawTetrisField(field *engine.TetrisField, ox, oy int) {
+	leftWallX := fieldPixelsWidth(field) - blockSize
+	grey := engine.TetrisBlockColor{R: 80, G: 80, B: 80}
+	for y := 0; y < field.Height+1; y++ {
+		drawBlock(ox, oy+y*blockSize, grey)
+		drawBlock(ox+leftWallX, oy+y*blockSize, grey)
+	}
+	bottomWallY := fieldPixelsHeight(field) - blockSize
+	for x := 0; x < field.Width; x++ {
+		drawBlock(ox+(x+1)*blockSize, oy+bottomWallY, grey)
+	}
+
+	ox += blockSize
+	for y := 0; y < field.Height; y++ {
+		for x := 0; x < field.Width; x++ {
+			offset := y*field.Width + x
+			drawTetrisBlock(&field.Blocks[offset], ox+x*blockSize, oy+y*blockSize)
+		}
+	}
+}
+
+func fieldPixelsWidth(field *engine.TetrisField) int {
+	return (field.Width + 2) * blockSize
+}
+
+func fieldPixelsHeight(field *engine.TetrisField) int {
+	return (field.Height + 1) * blockSize
+}
+
+// window size for the field size and the number of previews of the config
+func screenSize(config *engine.Config) (int, int) {
+	fieldH := (config.Height + 1) * blockSize
+	w := (config.Width+2)*blockSize + 2*sidePanelWidth
+	h := fieldH + 2*statusHeight
+	// the field is centred vertically and a long next queue reaches below
+	// it, it must end above the bottom status line
+	queueH := panelOffset + config.Previews*previewSpacing
+	if h < 2*(queueH+statusHeight)-fieldH {
+		h = 2*(queueH+statusHeight) - fieldH
+	}
+	if w < minScreenWidth {
+		w = minScreenWidth
+	}
+	if h < minScreenHeight {
+		h = minScreenHeight
+	}
+	return w, h
+}
+
+//-------------------------------------------------------------------------
+// Game
+//-------------------------------------------------------------------------
+
+// SDL frontend for the engine.GameSession
+type Game struct {
+	*engine.GameSession
+
+	cx, cy     int
+	screenW    int
+	screenH    int
+	gameOverCx int
+	pauseCx    int
+	pauseText  string
+	font       *Font
+	keys       KeyBindings
+	joy        *JoystickInput
+	viewer     *ReplayViewer // not nil when playing back a replay
+
+	// last line clear notification (a few short lines to fit left of the
+	// field) and its remaining time
+	clearNotice     []string
+	clearNoticeTime uint32
+
+	// high scores of the mode being played, nil when playing back a replay
+	scores       *HighScores
+	mode         string
+	table        string // see tableName
+	enteringName bool
+	name         string // last entered name, offered again the next time
+	showScores   bool
+	newScore     int // place of the score just added to the table or -1
+}
+
+func NewGame(config *engine.Config, font *Font, keys KeyBindings, joy JoyBindings,
+	scores *HighScores) *Game {
+	g := newGame(engine.NewGameSession(config, sessionSeed()), font, keys, joy)
+	g.scores = scores
+	g.name = os.Getenv("USER")
+	if len(g.name) > maxNameLength {
+		g.name = g.name[:maxNameLength]
+	}
+	g.OnEnd = g.gameEnded
+	if *replaysDir != "" {
+		g.StartRecording()
+	}
+	return g
+}
+
+func NewReplayGame(replay *engine.Replay, font *Font, keys KeyBindings) *Game {
+	viewer := NewReplayViewer(replay)
+	g := newGame(viewer.GameSession, font, keys, nil)
+	g.viewer = viewer
+	return g
+}
+
+func newGame(session *engine.GameSession, font *Font, keys KeyBindings, joy JoyBindings) *Game {
+	g := new(Game)
+	g.GameSession = session
+	config := session.Config()
+	g.mode = config.Mode
+	g.table = tableName(&config)
+	g.font = font
+	g.keys = keys
+	g.joy = NewJoystickInput(joy)
+	g.screenW, g.screenH = screenSize(&config)
+	g.cx = (g.screenW - fieldPixelsWidth(g.Field)) / 2
+	g.cy = (g.screenH - fieldPixelsHeight(g.Field)) / 2
+	g.gameOverCx = (g.screenW - font.Width("Game Over, restart? y/n")) / 2
+	g.pauseText = "Game paused"
+	if key, ok := keys.KeyFor(engine.Pause); ok {
+		g.pauseText += fmt.Sprintf(", press %s to resume", strings.ToUpper(keyName(key)))
+	}
+	g.pauseCx = (g.screenW - font.Width(g.pauseText)) / 2
+	g.OnClear = g.showClear
+	g.newScore = -1
+	return g
+}
+
+func (self *Game) showClear(e engine.ClearEvent) {
+	// e.g. "Back-to-Back T-Spin Double", "Combo 2", "+1500"
+	self.clearNotice = append(strings.Split(e.String(), ", "), fmt.Sprintf("+%d", e.Points))
+	self.clearNoticeTime = clearNoticeTime
+}
+
+func (self *Game) gameEnded() {
+	if self.Replay != nil {
+		if err := saveReplay(*replaysDir, self.Replay); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to save the replay:", err)
+		}
+	}
+	if result, ok := self.result(); ok && self.scores.Qualifies(self.table, timedModes[self.mode], &result) {
+		self.enteringName = true
+	}
+}
+
+// the high score entry for the game which just ended, false if the game
+// doesn't count (see goalModes)
+func (self *Game) result() (HighScore, bool) {
+	if unrankedModes[self.mode] {
+		return HighScore{}, false
+	}
+	if goalModes[self.mode] && self.State != engine.GS_Finished {
+		return HighScore{}, false
+	}
+	return HighScore{
+		Name:   self.name,
+		Score:  self.Score,
+		Lines:  self.Lines,
+		Level:  self.Level,
+		Time:   self.PlayTime,
+		Pieces: self.Pieces,
+		Date:   time.Now(),
+	}, true
+}
+
+func (self *Game) submitScore() {
+	self.enteringName = false
+	result, _ := self.result()
+	result.Name = strings.TrimSpace(self.name)
+	if result.Name == "" {
+		result.Name = "anonymous"
+	}
+	self.newScore = self.scores.Add(self.table, timedModes[self.mode], result)
+	if err := self.scores.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to save high scores:", err)
+	}
+	self.showScores = true
+}
+
+func (self *Game) Update(delta uint32) {
+	if self.viewer != nil {
+		self.viewer.Update(delta)
+	} else {
+		self.GameSession.Update(delta)
+	}
+	if self.clearNoticeTime > delta {
+		self.clearNoticeTime -= delta
+	} else {
+		self.clearNoticeTime = 0
+	}
+}
+
+// e.g. 1:02.345
+func formatTime(ms uint32) string {
+	return fmt.Sprintf("%d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+// the line at the top of the screen
+func (self *Game) Status() string {
+	switch self.mode {
+	case "sprint":
+		return fmt.Sprintf("Time: %s | Lines: %d/%d | PPS: %.2f", formatTime(self.PlayTime),
+			self.Lines, self.Config().LineGoal, self.PiecesPerSecond())
+	case "ultra":
+		return fmt.Sprintf("Time left: %s | Lines: %d | Score: %d", formatTime(self.TimeLeft()),
+			self.Lines, self.Score)
+	case "dig":
+		return fmt.Sprintf("Time: %s | Garbage: %d/%d | Pieces: %d", formatTime(self.PlayTime),
+			self.Garbage, self.Config().GarbageRows, self.Pieces)
+	case "survival":
+		return fmt.Sprintf("Time: %s | Next row in: %.1fs | Lines: %d | Score: %d",
+			formatTime(self.PlayTime), float64(self.NextGarbage())/1000, self.Lines, self.Score)
+	case "zen":
+		return fmt.Sprintf("Lines: %d | Score: %d", self.Lines, self.Score)
+	}
+	return fmt.Sprintf("Level: %d | Lines: %d | Score: %d", self.Level, self.Lines, self.Score)
+}
+
+//-------------------------------------------------------------------------
+// Game::HandleKey
+//-------------------------------------------------------------------------
+
+func (self *Game) restart() {
+	self.Seed = sessionSeed()
+	self.Reset()
+	self.enteringName = false
+	self.showScores = false
+	self.newScore = -1
+}
+
+// handles a pressed key or button bound to the action, returns false if the
+// game should quit
+func (self *Game) pressAction(action engine.Action) bool {
+	switch action {
+	case actionQuit:
+		// quitting from the pause screen is not allowed
+		return self.State == engine.GS_Paused
+	case actionRestart:
+		self.restart()
+	case actionScores:
+		if self.scores == nil {
+			break
+		}
+		if self.State == engine.GS_Playing || self.State == engine.GS_Clearing {
+			self.HandleAction(engine.Pause)
+		}
+		self.showScores = !self.showScores
+	default:
+		self.HandleAction(action)
+		if self.State == engine.GS_Playing || self.State == engine.GS_Clearing {
+			// resumed, the table would cover the field
+			self.showScores = false
+		}
+	}
+	return true
+}
+
+func (self *Game) releaseAction(action engine.Action) {
+	if action >= 0 {
+		self.ReleaseAction(action)
+	}
+}
+
+func (self *Game) HandleKey(key uint32) bool {
+	if self.viewer != nil {
+		return self.viewer.HandleKey(key)
+	}
+	if self.enteringName {
+		self.handleNameKey(key)
+		return true
+	}
+	if self.Ended() {
+		switch key {
+		case sdl.K_y:
+			self.restart()
+			return true
+		case sdl.K_n:
+			return false
+		}
+	}
+
+	if action, ok := self.keys[key]; ok {
+		return self.pressAction(action)
+	}
+	return true
+}
+
+// letters, digits and a few more characters which are allowed in names
+func nameChar(key uint32) (byte, bool) {
+	switch {
+	case key >= 'a' && key <= 'z', key >= '0' && key <= '9':
+		return byte(key), true
+	case key == sdl.K_SPACE, key == sdl.K_MINUS, key == sdl.K_PERIOD:
+		return byte(key), true
+	}
+	return 0, false
+}
+
+func (self *Game) handleNameKey(key uint32) {
+	switch key {
+	case sdl.K_RETURN, sdl.K_KP_ENTER:
+		self.submitScore()
+	case sdl.K_ESCAPE:
+		// the score doesn't go into the table
+		self.enteringName = false
+	case sdl.K_BACKSPACE:
+		if len(self.name) > 0 {
+			self.name = self.name[:len(self.name)-1]
+		}
+	default:
+		if c, ok := nameChar(key); ok && len(self.name) < maxNameLength {
+			self.name += string(c)
+		}
+	}
+}
+
+func (self *Game) HandleKeyUp(key uint32) {
+	if self.viewer != nil {
+		return
+	}
+	if action, ok := self.keys[key]; ok {
+		self.releaseAction(action)
+	}
+}
+
+// whether nothing is being played: the game is paused or over, or it's a
+// replay
+func (self *Game) Idle() bool {
+	return self.viewer != nil || self.State == engine.GS_Paused || self.Ended()
+}
+
+// Lets delta milliseconds pass and then handles the event (if any), the
+// time before an event must not count after it (e.g. towards DAS). Returns
+// false if the game should quit.
+func (self *Game) Step(delta uint32, event interface{}) bool {
+	self.Update(delta)
+	return event == nil || self.HandleEvent(event)
+}
+
+// dispatches an SDL event, returns false if the game should quit
+func (self *Game) HandleEvent(event interface{}) bool {
+	switch e := event.(type) {
+	case sdl.QuitEvent:
+		return false
+	case sdl.KeyboardEvent:
+		switch e.Type {
+		case sdl.KEYDOWN:
+			return self.HandleKey(e.Keysym.Sym)
+		case sdl.KEYUP:
+			self.HandleKeyUp(e.Keysym.Sym)
+		}
+	case sdl.JoyButtonEvent, sdl.JoyHatEvent:
+		return self.HandleJoyEvent(e)
+	}
+	return true
+}
+
+// the joysticks were reopened, hats start from scratch
+func (self *Game) ResetJoystick() {
+	releases := self.joy.Reset()
+	if self.viewer != nil {
+		// the replay has its own input
+		return
+	}
+	for _, a := range releases {
+		self.releaseAction(a.Action)
+	}
+}
+
+// handles joystick button and hat events, returns false if the game should
+// quit
+func (self *Game) HandleJoyEvent(event interface{}) bool {
+	if self.viewer != nil {
+		return true
+	}
+	running := true
+	for _, a := range self.joy.Translate(event) {
+		if !a.Pressed {
+			self.releaseAction(a.Action)
+		} else if !self.pressAction(a.Action) {
+			running = false
+		}
+	}
+	return running
+}
+
+//-------------------------------------------------------------------------
+// Game::Draw
+//-------------------------------------------------------------------------
+
+func (self *Game) Draw() {
+	switch self.State {
+	case engine.GS_Playing:
+		self.drawPlaying()
+	case engine.GS_GameOver:
+		self.drawGameOver()
+	case engine.GS_Paused:
+		self.drawGamePaused()
+	case engine.GS_Clearing:
+		self.drawClearing()
+	case engine.GS_Finished:
+		self.drawFinished()
+	}
+	if self.showScores {
+		self.drawHighScores()
+	}
+}
+
+func (self *Game) drawPlaying() {
+	drawTetrisField(self.Field, self.cx, self.cy)
+	// while lines are cleared (or that is paused) the figure is merged
+	// already and the next one isn't there yet
+	if self.ClearingRows == nil {
+		if *ghost {
+			drawGhostFigure(self.GhostFigure(), self.cx, self.cy)
+		}
+		drawTetrisFigure(self.Figure, self.cx, self.cy)
+	}
+
+	gl.Color3ub(255, 255, 255)
+	self.font.Draw(self.cx+fieldPixelsWidth(self.Field)+50, self.cy+5, "Next:")
+	for i, figure := range self.Queue {
+		drawFigureAt(figure, self.cx+fieldPixelsWidth(self.Field)+4*blockSize, self.cy+panelOffset+i*previewSpacing)
+	}
+
+	// the queue takes the whole right side, hold goes to the left
+	if self.CanHold() {
+		gl.Color3ub(255, 255, 255)
+	} else {
+		gl.Color3ub(120, 120, 120)
+	}
+	self.font.Draw(self.cx-100, self.cy+5, "Hold:")
+	if self.HoldFigure != nil {
+		drawFigureAt(self.HoldFigure, self.cx-70, self.cy+panelOffset)
+	}
+
+	if self.mode == "ultra" {
+		// the countdown turns red for the last 10 seconds
+		if self.TimeLeft() <= 10000 {
+			gl.Color3ub(255, 60, 60)
+		} else {
+			gl.Color3ub(255, 255, 255)
+		}
+		clock := formatTime(self.TimeLeft())
+		self.font.Draw(self.panelX(clock), self.cy+125, clock)
+	}
+
+	if self.clearNoticeTime > 0 {
+		gl.Color3ub(255, 255, 0)
+		for i, line := range self.clearNotice {
+			self.font.Draw(self.panelX(line), self.cy+155+i*20, line)
+		}
+	}
+}
+
+// x of the text centred in the space left of the field, long texts stick
+// to the left edge of the window instead of going past it
+func (self *Game) panelX(text string) int {
+	x := (self.cx - self.font.Width(text)) / 2
+	if x < 5 {
+		x = 5
+	}
+	return x
+}
+
+// complete lines flash white and fade away before they collapse
+func (self *Game) drawClearing() {
+	self.drawPlaying()
+
+	progress := self.ClearingProgress()
+	if progress > 1 {
+		progress = 1
+	}
+	alpha := uint8(255 * (1 - progress))
+	x := self.cx + blockSize
+	w := self.Field.Width * blockSize
+	gl.Color4ub(255, 255, 255, alpha)
+	gl.Begin(gl.QUADS)
+	for _, row := range self.ClearingRows {
+		y := self.cy + row*blockSize
+		gl.Vertex2i(x, y)
+		gl.Vertex2i(x+w, y)
+		gl.Vertex2i(x+w, y+blockSize)
+		gl.Vertex2i(x, y+blockSize)
+	}
+	gl.End()
+}
+
+// the name entry line if there is a new high score, returns false if it's
+// not there
+func (self *Game) drawNameEntry() bool {
+	if !self.enteringName {
+		return false
+	}
+	gl.Color3ub(255, 255, 0)
+	text := fmt.Sprintf("New high score! Your name: %s_", self.name)
+	self.font.Draw((self.screenW-self.font.Width(text))/2, promptY, text)
+	return true
+}
+
+func (self *Game) drawGameOver() {
+	self.drawPlaying()
+	if self.viewer != nil {
+		// the end of the replay, the status line says the rest
+		return
+	}
+	if self.drawNameEntry() {
+		return
+	}
+	gl.Color3ub(200, 0, 0)
+	self.font.Draw(self.gameOverCx, promptY, "Game Over, restart? y/n")
+	seed := fmt.Sprintf("Seed: %d", self.Seed)
+	self.font.Draw((self.screenW-self.font.Width(seed))/2, self.screenH-resultsBottom, seed)
+}
+
+// the table of the current mode and rules on a dark background in the
+// middle of the screen, the score just added is highlighted
+func (self *Game) drawHighScores() {
+	const w, h = 400, 260
+	x, y := (self.screenW-w)/2, (self.screenH-h)/2
+	gl.Color4ub(0, 0, 0, 220)
+	gl.Begin(gl.QUADS)
+	gl.Vertex2i(x, y)
+	gl.Vertex2i(x+w, y)
+	gl.Vertex2i(x+w, y+h)
+	gl.Vertex2i(x, y+h)
+	gl.End()
+
+	gl.Color3ub(255, 255, 255)
+	title := "High scores: " + self.table
+	self.font.Draw(x+(w-self.font.Width(title))/2, y+10, title)
+	table := self.scores.Table(self.table)
+	switch {
+	case unrankedModes[self.mode]:
+		self.font.Draw(x+20, y+40, "This mode keeps no high scores")
+	case len(table) == 0:
+		self.font.Draw(x+20, y+40, "No scores yet")
+	}
+	for i, e := range table {
+		if i == self.newScore {
+			gl.Color3ub(255, 255, 0)
+		} else {
+			gl.Color3ub(255, 255, 255)
+		}
+		ry := y + 40 + i*20
+		// timed modes have the same goal in lines, pieces say more
+		score, count := fmt.Sprint(e.Score), e.Lines
+		if timedModes[self.mode] {
+			score, count = formatTime(e.Time), e.Pieces
+		}
+		self.font.Draw(x+20, ry, fmt.Sprintf("%d.", i+1))
+		self.font.Draw(x+50, ry, e.Name)
+		self.font.Draw(x+240-self.font.Width(score), ry, score)
+		self.font.Draw(x+260, ry, fmt.Sprint(count))
+		self.font.Draw(x+300, ry, e.Date.Format("2006-01-02"))
+	}
+}
+
+// results of a game which reached its goal
+func (self *Game) drawFinished() {
+	self.drawPlaying()
+	results := fmt.Sprintf("Time: %s | Pieces: %d, %.2f per second | Finesse faults: %d",
+		formatTime(self.PlayTime), self.Pieces, self.PiecesPerSecond(), self.FinesseFaults)
+	if self.mode == "ultra" {
+		results = fmt.Sprintf("Score: %d | Lines: %d | Pieces: %d, %.2f per second | Finesse faults: %d",
+			self.Score, self.Lines, self.Pieces, self.PiecesPerSecond(), self.FinesseFaults)
+	}
+	gl.Color3ub(255, 255, 255)
+	self.font.Draw((self.screenW-self.font.Width(results))/2, self.screenH-resultsBottom, results)
+	if self.viewer != nil || self.drawNameEntry() {
+		return
+	}
+	gl.Color3ub(0, 200, 0)
+	text := "Finished! Restart? y/n"
+	if self.mode == "ultra" {
+		text = "Time's up! Restart? y/n"
+	}
+	self.font.Draw((self.screenW-self.font.Width(text))/2, promptY, text)
+}
+
+func (self *Game) drawGamePaused() {
+	self.drawPlaying()
+	gl.Color3ub(200, 200, 0)
+	self.font.Draw(self.pauseCx, promptY, self.pauseText)
+}
+
+//-------------------------------------------------------------------------
+// main()
+//-------------------------------------------------------------------------
+
+func main() {
+	runtime.LockOSThread()
+	flag.Parse()
+
+	keys, err := LoadKeyBindings(*keysFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	joy, err := LoadJoyBindings(*joyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	config := engine.DefaultConfig()
+	config.Mode = *mode
+	config.Width = *fieldWidth
+	config.Height = *fieldHeight
+	config.LineGoal = *lineGoal
+	config.TimeLimit = uint32(*timeLimit) * 1000
+	config.GarbageRows = *garbageRows
+	config.Level = *initLevel
+	config.Randomizer = *randomizer
+	config.Rotation = *rotation
+	config.LockDelay = uint32(*lockDelay)
+	config.LockResets = *lockResets
+	config.LineClearDelay = uint32(*lineClearDelay)
+	config.Previews = *previews
+	config.DAS = uint32(*das)
+	config.ARR = uint32(*arr)
+	config.SoftDropFactor = *softDropFactor
+	if err := config.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	scores, err := LoadHighScores(*scoresFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var replay *engine.Replay
+	if *replayFile != "" {
+		replay, err = loadReplay(*replayFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	sdl.Init(sdl.INIT_VIDEO | sdl.INIT_JOYSTICK)
+	defer sdl.Quit()
+
+	sdl.GL_SetAttribute(sdl.GL_SWAP_CONTROL, 1)
+
+	// replays bring their own field size
+	screenW, screenH := screenSize(config)
+	if replay != nil {
+		screenW, screenH = screenSize(&replay.Config)
+	}
+	if sdl.SetVideoMode(screenW, screenH, 32, sdl.OPENGL) == nil {
+		panic("sdl error")
+	}
+
+	sdl.WM_SetCaption("Gotris", "Gotris")
+
+	gl.Enable(gl.TEXTURE_2D)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	gl.Viewport(0, 0, screenW, screenH)
+	gl.MatrixMode(gl.PROJECTION)
+	gl.LoadIdentity()
+	gl.Ortho(0, float64(screenW), float64(screenH), 0, -1, 1)
+
+	gl.ClearColor(0, 0, 0, 0)
+
+	//-----------------------------------------------------------------------------
+
+	font, err := LoadFontFromFile("dejavu.font")
+	if err != nil {
+		panic(err)
+	}
+
+	var joysticks Joysticks
+	joysticks.Open()
+	defer joysticks.Close()
+
+	var gs *Game
+	if replay != nil {
+		gs = NewReplayGame(replay, font, keys)
+	} else {
+		gs = NewGame(config, font, keys, joy, scores)
+	}
+	lastTime := sdl.GetTicks()
+	ticker := time.NewTicker(10 * time.Millisecond)
+
+	// events are handled on the same goroutine as updates, the session
+	// (and the replay it records) must see input and time pass in order
+	for {
+		var event interface{}
+		select {
+		case <-ticker.C:
+		case event = <-sdl.Events:
+		}
+
+		now := sdl.GetTicks()
+		delta := now - lastTime
+		lastTime = now
+
+		if !gs.Step(delta, event) {
+			break
+		}
+		if joysticks.Update(delta, gs.Idle()) {
+			gs.ResetJoystick()
+		}
+
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		font.Draw(5, statusY, gs.Status())
+		gs.Draw()
+		if gs.viewer != nil {
+			gl.Color3ub(255, 255, 255)
+			font.Draw(5, screenH-replayBottom, gs.viewer.Status())
+		}
+		gl.Color3ub(255, 255, 255)
+		sdl.GL_SwapBuffers()
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/nsf/gotris/engine"
+	"testing"
+)
+
+func TestScreenSizeFitsQueue(t *testing.T) {
+	config := engine.DefaultConfig()
+	for _, height := range []int{engine.MinHeight, 25, engine.MaxHeight} {
+		for previews := 1; previews <= engine.MaxPreviews; previews++ {
+			config.Height, config.Previews = height, previews
+			_, h := screenSize(config)
+			cy := (h - (height+1)*blockSize) / 2
+			if bottom := cy + panelOffset + previews*previewSpacing; bottom > h-statusHeight {
+				t.Errorf("height %d, %d previews: the queue ends at %d on a %d pixels high screen",
+					height, previews, bottom, h)
+			}
+		}
+	}
+}
+
+func TestTableNames(t *testing.T) {
+	for _, c := range []struct {
+		change func(c *engine.Config)
+		want   string
+	}{
+		{func(c *engine.Config) {}, "marathon, level 1, 10x25"},
+		{func(c *engine.Config) { c.Level = 5 }, "marathon, level 5, 10x25"},
+		{func(c *engine.Config) { c.Mode = "sprint" }, "sprint 40 lines, 10x25"},
+		{func(c *engine.Config) { c.Mode, c.LineGoal = "sprint", 1 }, "sprint 1 lines, 10x25"},
+		{func(c *engine.Config) { c.Mode, c.TimeLimit = "ultra", 90000 }, "ultra 1:30, level 1, 10x25"},
+		{func(c *engine.Config) { c.Mode, c.GarbageRows = "dig", 5 }, "dig 5 rows, 10x25"},
+		{func(c *engine.Config) { c.Mode, c.Width = "dig", 4 }, "dig 10 rows, 4x25"},
+	} {
+		config := engine.DefaultConfig()
+		c.change(config)
+		if name := tableName(config); name != c.want {
+			t.Errorf("table %q, want %q", name, c.want)
+		}
+	}
+}
+
+func TestScoresPauseLineClear(t *testing.T) {
+	g := newTestGame()
+	g.scores = &HighScores{Tables: make(map[string][]HighScore)}
+	bottom := g.Field.Blocks[(g.Field.Height-1)*g.Field.Width:]
+	for i := range bottom {
+		bottom[i].Filled = true
+	}
+	g.HandleAction(engine.HardDrop)
+	if g.State != engine.GS_Clearing {
+		t.Fatalf("state %d after completing a line, want GS_Clearing", g.State)
+	}
+
+	g.pressAction(actionScores)
+	if g.State != engine.GS_Paused || !g.showScores {
+		t.Fatalf("state %d, table shown: %v; want a pause with the table", g.State, g.showScores)
+	}
+	g.pressAction(engine.Pause)
+	if g.State != engine.GS_Clearing || g.showScores {
+		t.Fatalf("state %d, table shown: %v; want the line clear going on without the table",
+			g.State, g.showScores)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// $XDG_CONFIG_HOME/gotris/<name> or ~/.config/gotris/<name>
+func configPath(name string) string {
+	return xdgPath("XDG_CONFIG_HOME", ".config", name)
+}
+
+// $XDG_DATA_HOME/gotris/<name> or ~/.local/share/gotris/<name>
+func dataPath(name string) string {
+	return xdgPath("XDG_DATA_HOME", filepath.Join(".local", "share"), name)
+}
+
+func xdgPath(env, fallback, name string) string {
+	dir := os.Getenv(env)
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), fallback)
+	}
+	return filepath.Join(dir, "gotris", name)
+}
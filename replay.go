@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"github.com/0xe2-0x9a-0x9b/Go-SDL/sdl"
+	"github.com/nsf/gotris/engine"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// writes the replay into the dir (creating it if needed) under a name made
+// of the current date, time and score
+func saveReplay(dir string, replay *engine.Replay) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d.json", time.Now().Format("2006-01-02_15-04-05"), replay.Score)
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	if err := replay.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func loadReplay(filename string) (*engine.Replay, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	replay, err := engine.ReadReplay(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return replay, nil
+}
+
+//-------------------------------------------------------------------------
+// ReplayViewer
+//-------------------------------------------------------------------------
+
+var replaySpeeds = [...]float64{0.25, 0.5, 1, 2, 4, 8}
+
+const (
+	normalReplaySpeed = 2 // index of 1x in replaySpeeds
+	replaySeekStep    = 5000
+)
+
+// Plays a replay back in real time (scaled by the chosen speed) and handles
+// the playback controls:
+//
+//	space        pause/resume
+//	up, down     faster/slower
+//	left, right  seek 5 seconds back/forward
+//	home, end    seek to the start/end
+//	escape       quit
+type ReplayViewer struct {
+	*engine.ReplayPlayer
+
+	speed  int     // index into replaySpeeds
+	carry  float64 // scaled time left over from the last Update, ms
+	paused bool
+}
+
+func NewReplayViewer(replay *engine.Replay) *ReplayViewer {
+	return &ReplayViewer{
+		ReplayPlayer: engine.NewReplayPlayer(replay),
+		speed:        normalReplaySpeed,
+	}
+}
+
+func (self *ReplayViewer) Update(delta uint32) {
+	if self.paused {
+		return
+	}
+	t := float64(delta)*replaySpeeds[self.speed] + self.carry
+	ms := uint32(t)
+	self.carry = t - float64(ms)
+	self.Advance(ms)
+}
+
+// returns false if the viewer should quit
+func (self *ReplayViewer) HandleKey(key uint32) bool {
+	switch key {
+	case sdl.K_ESCAPE:
+		return false
+	case sdl.K_SPACE:
+		self.paused = !self.paused
+	case sdl.K_UP:
+		if self.speed < len(replaySpeeds)-1 {
+			self.speed++
+		}
+	case sdl.K_DOWN:
+		if self.speed > 0 {
+			self.speed--
+		}
+	case sdl.K_LEFT:
+		if self.Time > replaySeekStep {
+			self.SeekTime(self.Time - replaySeekStep)
+		} else {
+			self.Seek(0)
+		}
+	case sdl.K_RIGHT:
+		self.SeekTime(self.Time + replaySeekStep)
+	case sdl.K_HOME:
+		self.Seek(0)
+	case sdl.K_END:
+		self.Seek(self.Frames())
+	}
+	return true
+}
+
+// playback status line, e.g.
+// "Frame 1200/5031 | 0:15.4 | 2x | Input: move-left (last: hard-drop)"
+func (self *ReplayViewer) Status() string {
+	s := fmt.Sprintf("Frame %d/%d | %d:%04.1f | %gx", self.Frame, self.Frames(),
+		self.Time/60000, float64(self.Time%60000)/1000, replaySpeeds[self.speed])
+	if self.paused {
+		s += " paused"
+	}
+
+	held, last := self.Input()
+	input := make([]string, 0, len(held)+1)
+	for _, action := range held {
+		input = append(input, action.String())
+	}
+	if last != nil {
+		input = append(input, "(last: "+last.Action.String()+")")
+	}
+	return s + " | Input: " + strings.Join(input, " ")
+}